@@ -0,0 +1,108 @@
+package cfgset
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/codec"
+	"github.com/davidalpert/go-deep-merge/v1"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+// decodeLayers parses files as layers of a single YAML stream so that each
+// file can reference the anchors defined by the files before it (e.g. an
+// override can alias an anchor from default.yaml) and returns the root
+// node of each file; an empty file returns nil
+//
+// files in other formats are decoded with their codec and converted to
+// nodes so that formats can be mixed within an app folder
+func decodeLayers(fs afero.Fs, files []string) ([]*yaml.Node, error) {
+	layers := make([]*yaml.Node, len(files))
+	yamlFiles := make([]string, 0, len(files))
+	yamlLayers := make([]int, 0, len(files))
+	sb := strings.Builder{}
+	for i, f := range files {
+		b, err := afero.ReadFile(fs, f)
+		if err != nil {
+			return nil, fmt.Errorf("read file %#v: %#v", f, err)
+		}
+		if c, ok := codec.ForFile(f); ok && c != codec.YAML {
+			if layers[i], err = decodeOtherLayer(f, b); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		yamlFiles = append(yamlFiles, f)
+		yamlLayers = append(yamlLayers, i)
+
+		// each file becomes an item of a sequence, indented below its "-"
+		sb.WriteString("-\n")
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, "%") || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "...") {
+				// directives and document markers only make sense at the top level
+				continue
+			}
+			sb.WriteString("  ")
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	}
+	if len(yamlFiles) == 0 {
+		return layers, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(sb.String()), &doc); err != nil {
+		return nil, layerError(fs, yamlFiles, err)
+	}
+
+	for i, n := range doc.Content[0].Content {
+		if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+			continue
+		}
+		if n.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("unmarshalling %#v: expected a hash at the top level", yamlFiles[i])
+		}
+		layers[yamlLayers[i]] = n
+	}
+	return layers, nil
+}
+
+// decodeOtherLayer decodes a file which is not YAML into a node
+func decodeOtherLayer(file string, b []byte) (*yaml.Node, error) {
+	m, err := codec.Decode(file, b)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling %#v: %#v", file, err)
+	}
+	var n yaml.Node
+	if err := n.Encode(m); err != nil {
+		return nil, fmt.Errorf("unmarshalling %#v: %#v", file, err)
+	}
+	return &n, nil
+}
+
+// layerError finds the file which caused err, parsing each file on its own
+// for an error which does not come from referencing another file's anchors
+func layerError(fs afero.Fs, files []string, err error) error {
+	for _, f := range files {
+		b, _ := afero.ReadFile(fs, f)
+		var n yaml.Node
+		if ferr := yaml.Unmarshal(b, &n); ferr != nil && !strings.Contains(ferr.Error(), "unknown anchor") {
+			return fmt.Errorf("unmarshalling %#v: %#v", f, ferr)
+		}
+	}
+	return fmt.Errorf("unmarshalling %s: %#v", strings.Join(files, " -> "), err)
+}
+
+// decodeLayer decodes a layer returned by decodeLayers into a map, deep merging its merge keys (<<)
+func decodeLayer(n *yaml.Node, file string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if n == nil {
+		return m, nil
+	}
+	v, err := v1.DecodeNode(n, v1.NewConfigDeeperMergeBang().WithMergeHashArrays(true).WithDeepMergeKeys(true))
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling %#v: %#v", file, err)
+	}
+	return v.(map[string]interface{}), nil
+}
@@ -0,0 +1,38 @@
+// Package cfgset merges a folder of config files laid out by convention
+// into one config per app and slug (e.g. environment)
+//
+// the source folder holds one folder per app; each app folder holds a
+// default file and one file per slug:
+//
+//	config/
+//	  goconfig.yaml        (optional, see Manifest)
+//	  _global/             (optional, shared by every app)
+//	    default.yaml
+//	    prd.yaml
+//	  app1/
+//	    default.yaml
+//	    dev.yaml
+//	    dev.us-east-1.yaml
+//	    prd.json
+//
+// Merge deep merges each slug onto the defaults (hashes are merged key by
+// key, arrays are combined and the hashes within them merged, other values
+// are replaced) and a dotted slug such as dev.us-east-1.blue is merged
+// onto the merge of its nearest existing ancestor (dev.us-east-1, or dev);
+// files can be written in any format registered with the codec package and
+// formats can be mixed within an app folder
+//
+// YAML files can alias the anchors defined by the files they are merged
+// onto and merge keys (<<) are deep merged; each MergeResult records the
+// provenance of every key and, with MergeOptions.PreserveFormatting, a
+// yaml.Node which keeps the comments, key order and style of the files
+//
+// any hash in a file can deep merge another file into itself with a
+// $include key and any value can be replaced by another file with the
+// !include tag; paths are relative to the including file, which can
+// itself be included, and the provenance of an included value names the
+// chain of files it was included through
+//
+// every file is read from the afero.Fs given to Merge so an app can merge
+// config from an embedded or in-memory file system as easily as from disk
+package cfgset
@@ -0,0 +1,295 @@
+package cfgset
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/codec"
+	"github.com/davidalpert/go-deep-merge/v1"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"path"
+	"strings"
+)
+
+const (
+	// IncludeKey is the key of a hash which deep merges the file (or list of
+	// files) it names underneath the other keys of that hash
+	//
+	//	logging:
+	//	  $include: ../_shared/logging.yaml
+	//	  level: DEBUG
+	IncludeKey = "$include"
+	// IncludeTag is the YAML tag of a value replaced by the file it names
+	//
+	//	logging: !include ../_shared/logging.yaml
+	IncludeTag = "!include"
+	// IncludeSeparator joins the file which includes a value to the file
+	// (or chain of files) it was included from in the Source of its
+	// provenance (e.g. config/app1/default.yaml > config/_shared/logging.yaml)
+	IncludeSeparator = " > "
+)
+
+// Include records a file included into another
+type Include struct {
+	// File is the included file
+	File string
+	// IncludedBy is the file which includes it
+	IncludedBy string
+	// Path is the dotted key path in IncludedBy where File is merged (empty for the top level)
+	Path string
+}
+
+// includer replaces the include directives of a layer by the files they include
+type includer struct {
+	fs       afero.Fs
+	cfg      *v1.Config
+	includes []Include
+}
+
+// expandIncludes replaces the include directives of each layer by the
+// files they include and returns the files included by each layer along
+// with the include chain of each leaf value which came from them, by its
+// dotted key path
+func expandIncludes(fs afero.Fs, layers []*yaml.Node, files []string, cfg *v1.Config) ([]map[string]string, []Include, error) {
+	in := includer{fs: fs, cfg: cfg, includes: make([]Include, 0)}
+	origins := make([]map[string]string, len(layers))
+	for i, n := range layers {
+		origins[i] = make(map[string]string)
+		if n == nil {
+			continue
+		}
+		if err := in.expand(n, files[i], nil, []string{files[i]}, origins[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return origins, in.includes, nil
+}
+
+// expand replaces the include directives below n (at p in file) in place
+// and records in origins the include chain of each leaf value they wrote;
+// stack holds the files including file
+func (in *includer) expand(n *yaml.Node, file string, p v1.Path, stack []string, origins map[string]string) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			if err := in.expand(c, file, p, stack, origins); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			if err := in.expand(c, file, p.Index(i), stack, origins); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if n.Tag != IncludeTag {
+			return nil
+		}
+		included, includedOrigins, err := in.include(n.Value, file, p, stack)
+		if err != nil {
+			return err
+		}
+		if included == nil {
+			included = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+		}
+		anchor := n.Anchor
+		*n = *included
+		n.Anchor = anchor
+		for k, chain := range includedOrigins {
+			origins[k] = chain
+		}
+	case yaml.MappingNode:
+		var targets []string
+		content := make([]*yaml.Node, 0, len(n.Content))
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Kind == yaml.ScalarNode && k.Value == IncludeKey {
+				t, err := includeTargets(v)
+				if err != nil {
+					return fmt.Errorf("%#v at %#v: %v", file, p.Key(k.Value).String(), err)
+				}
+				targets = append(targets, t...)
+				continue
+			}
+			if err := in.expand(v, file, p.Key(k.Value), stack, origins); err != nil {
+				return err
+			}
+			content = append(content, k, v)
+		}
+		if targets == nil {
+			return nil
+		}
+		n.Content = content
+
+		// the included files are merged in order, then the other keys on top
+		merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		includedOrigins := make(map[string]string)
+		for _, t := range targets {
+			included, o, err := in.include(t, file, p, stack)
+			if err != nil {
+				return err
+			}
+			if included == nil {
+				continue
+			}
+			if included.Kind != yaml.MappingNode {
+				return fmt.Errorf("%#v at %#v: %s %#v: expected a hash at the top level", file, p.String(), IncludeKey, t)
+			}
+			overlayOrigins(includedOrigins, mappingLeaves(included, p), o)
+			if merged, err = in.merge(included, merged); err != nil {
+				return fmt.Errorf("%#v at %#v: %s %#v: %v", file, p.String(), IncludeKey, t, err)
+			}
+		}
+		overlayOrigins(includedOrigins, mappingLeaves(n, p), nil)
+		for k, chain := range includedOrigins {
+			origins[k] = chain
+		}
+
+		result, err := in.merge(n, merged)
+		if err != nil {
+			return fmt.Errorf("%#v at %#v: %s: %v", file, p.String(), IncludeKey, err)
+		}
+		anchor := n.Anchor
+		*n = *result
+		n.Anchor = anchor
+	}
+	return nil
+}
+
+// include decodes the file named by target (relative to file) with its own
+// include directives expanded and returns it with the include chain of
+// each of its leaf values
+func (in *includer) include(target string, file string, p v1.Path, stack []string) (*yaml.Node, map[string]string, error) {
+	name := target
+	if !path.IsAbs(name) {
+		name = path.Join(path.Dir(file), target)
+	}
+	for i, f := range stack {
+		if f == name {
+			return nil, nil, fmt.Errorf("%#v includes itself: %s", name, strings.Join(append(append([]string(nil), stack[i:]...), name), IncludeSeparator))
+		}
+	}
+
+	b, err := afero.ReadFile(in.fs, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%#v at %#v: including %#v: %#v", file, p.String(), target, err)
+	}
+	n, err := decodeIncludedFile(name, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	in.includes = append(in.includes, Include{File: name, IncludedBy: file, Path: p.String()})
+	if n == nil {
+		return nil, nil, nil
+	}
+
+	nested := make(map[string]string)
+	if err := in.expand(n, name, p, append(append([]string(nil), stack...), name), nested); err != nil {
+		return nil, nil, err
+	}
+	origins := make(map[string]string)
+	for _, leaf := range nodeLeaves(n, p) {
+		origins[leaf] = name
+		if chain, ok := nested[leaf]; ok {
+			origins[leaf] = name + IncludeSeparator + chain
+		}
+	}
+	return n, origins, nil
+}
+
+// merge deep merges the src node onto the dest node
+func (in *includer) merge(src, dest *yaml.Node) (*yaml.Node, error) {
+	doc, err := v1.MergeNodesWithOptions(src, dest, in.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// includeTargets returns the file or list of files named by the value of an include key
+func includeTargets(v *yaml.Node) ([]string, error) {
+	switch v.Kind {
+	case yaml.ScalarNode:
+		return []string{v.Value}, nil
+	case yaml.SequenceNode:
+		targets := make([]string, 0, len(v.Content))
+		for _, c := range v.Content {
+			if c.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("expected a file or a list of files")
+			}
+			targets = append(targets, c.Value)
+		}
+		return targets, nil
+	}
+	return nil, fmt.Errorf("expected a file or a list of files")
+}
+
+// decodeIncludedFile decodes an included file on its own into a node; an empty file returns nil
+func decodeIncludedFile(file string, b []byte) (*yaml.Node, error) {
+	if c, ok := codec.ForFile(file); ok && c != codec.YAML {
+		return decodeOtherLayer(file, b)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshalling %#v: %#v", file, err)
+	}
+	if len(doc.Content) == 0 || (doc.Content[0].Kind == yaml.ScalarNode && doc.Content[0].Tag == "!!null") {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// nodeLeaves returns the dotted key path of each leaf value below n at p
+// in the form recorded by v1.Provenance: arrays, scalars and empty hashes
+// are leaves
+func nodeLeaves(n *yaml.Node, p v1.Path) []string {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode || len(n.Content) == 0 {
+		return []string{p.String()}
+	}
+	leaves := make([]string, 0)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "<<" {
+			// merge keys are attributed to the file which uses them
+			continue
+		}
+		leaves = append(leaves, nodeLeaves(n.Content[i+1], p.Key(n.Content[i].Value))...)
+	}
+	return leaves
+}
+
+// mappingLeaves returns the leaves below the mapping n at p, which has none when it is empty
+func mappingLeaves(n *yaml.Node, p v1.Path) []string {
+	if len(n.Content) == 0 {
+		return nil
+	}
+	return nodeLeaves(n, p)
+}
+
+// overlayOrigins records that the leaves were written on top of origins:
+// the include chain of each leaf found in chains replaces those of the
+// leaves it overrides, and leaves without one are not included
+func overlayOrigins(origins map[string]string, leaves []string, chains map[string]string) {
+	for _, leaf := range leaves {
+		for k := range origins {
+			if k == leaf || strings.HasPrefix(k, leaf+".") || strings.HasPrefix(k, leaf+"[") || strings.HasPrefix(leaf, k+".") || strings.HasPrefix(leaf, k+"[") {
+				delete(origins, k)
+			}
+		}
+		if chain, ok := chains[leaf]; ok {
+			origins[leaf] = chain
+		}
+	}
+}
+
+// attributeIncludes rewrites the source of each value which file wrote
+// from one of the files it includes to the include chain it came from
+func attributeIncludes(p v1.Provenance, file string, origins map[string]string) {
+	for k, chain := range origins {
+		if o, ok := p[k]; ok && o.Source == file {
+			o.Source = file + IncludeSeparator + chain
+		}
+	}
+}
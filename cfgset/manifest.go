@@ -0,0 +1,217 @@
+package cfgset
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/v1"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"path"
+)
+
+// ManifestFile is the name of the optional manifest in a source folder
+const ManifestFile = "goconfig.yaml"
+
+// Manifest describes the hierarchy of the apps in a source folder and how
+// they are written; the top-level hierarchy applies to every app and each
+// app can extend it
+//
+//	layers:
+//	  default: [default.yaml, "defaults/*.yaml"]
+//	slugs:
+//	  prd-eu: [default, prd, eu]
+//	merge:
+//	  overwrite_arrays: true
+//	apps:
+//	  app1:
+//	    out_format: json
+//	    slugs:
+//	      dev: [default, dev]
+//	  app1-high:
+//	    extends: app1
+//
+// an app folder can hold its own manifest with the settings of that app
+// (e.g. app1-high/goconfig.yaml holding "extends: app1"), which win over
+// those under apps; without a manifest (or for the parts of the hierarchy it leaves out) the
+// filename convention described by Merge applies
+type Manifest struct {
+	Hierarchy `yaml:",inline"`
+	Apps      map[string]AppManifest `yaml:"apps"`
+}
+
+// AppManifest describes the hierarchy of one app and how it is written
+type AppManifest struct {
+	Hierarchy `yaml:",inline"`
+	// OutFormat overrides the output format of the app (e.g. json, toml, configmap)
+	OutFormat string `yaml:"out_format"`
+	// Extends names the app whose merged slugs this app's files are merged onto
+	Extends string `yaml:"extends"`
+}
+
+// Hierarchy declares the layers merged for each slug of an app
+type Hierarchy struct {
+	// Layers maps a layer name to the file globs (relative to the app
+	// folder) merged in order for that layer; a layer which is not declared
+	// is the file named by the layer (e.g. prd is prd.yaml); globs can
+	// reach files shared by several apps (e.g. ../_shared/logging.yaml)
+	Layers map[string][]string `yaml:"layers"`
+	// Slugs maps each slug to the layers merged in order for it (e.g.
+	// prd-eu: [default, prd, eu]); another slug named among the layers
+	// stands for all of its layers (e.g. prd-eu: [prd, eu]); when set, only
+	// these slugs are merged
+	Slugs map[string][]string `yaml:"slugs"`
+	// Merge configures how the layers are merged
+	Merge *MergeManifest `yaml:"merge"`
+}
+
+// MergeManifest configures how layers are merged onto each other
+type MergeManifest struct {
+	// OverwriteArrays set to true to replace arrays rather than combine them
+	OverwriteArrays bool `yaml:"overwrite_arrays"`
+	// KeepArrayDuplicates set to true to keep duplicate entries when arrays are combined
+	KeepArrayDuplicates bool `yaml:"keep_array_duplicates"`
+	// SortMergedArrays set to true to sort combined arrays
+	SortMergedArrays bool `yaml:"sort_merged_arrays"`
+	// KnockoutPrefix set to a prefix (e.g. "--") which deletes the key or array element it prefixes
+	KnockoutPrefix string `yaml:"knockout_prefix"`
+	// DeleteNilValues set to true to delete the key of each null value
+	DeleteNilValues bool `yaml:"delete_nil_values"`
+	// Strategies applies a merge strategy to the values at paths matching a pattern; later matches win
+	Strategies []StrategyManifest `yaml:"strategies"`
+}
+
+// StrategyManifest pairs a path pattern (e.g. spec.containers) with a merge
+// strategy (e.g. replace, union, append or merge-by-key:name)
+type StrategyManifest struct {
+	Path     string `yaml:"path"`
+	Strategy string `yaml:"strategy"`
+}
+
+// ReadManifest reads the manifest in sourceFolder and those in its app
+// folders, returning an empty manifest when there is none
+func ReadManifest(fs afero.Fs, sourceFolder string) (Manifest, error) {
+	m := Manifest{Apps: make(map[string]AppManifest)}
+	filename := path.Join(sourceFolder, ManifestFile)
+	ok, err := afero.Exists(fs, filename)
+	if err != nil {
+		return m, err
+	}
+	if ok {
+		b, err := afero.ReadFile(fs, filename)
+		if err != nil {
+			return m, fmt.Errorf("reading %#v: %#v", filename, err)
+		}
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return m, fmt.Errorf("unmarshalling %#v: %#v", filename, err)
+		}
+		if m.Apps == nil {
+			m.Apps = make(map[string]AppManifest)
+		}
+	}
+
+	if ok, err := afero.DirExists(fs, sourceFolder); err != nil || !ok {
+		return m, err
+	}
+	apps, err := appFolders(fs, sourceFolder, m.Apps)
+	if err != nil {
+		return m, err
+	}
+	return m, m.readAppManifests(fs, apps)
+}
+
+// readAppManifests reads the manifest in each app folder, if any, onto the
+// settings of that app in m
+func (m Manifest) readAppManifests(fs afero.Fs, apps []appFolder) error {
+	for _, app := range apps {
+		filename := path.Join(app.dir, ManifestFile)
+		if ok, err := afero.Exists(fs, filename); err != nil || !ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := afero.ReadFile(fs, filename)
+		if err != nil {
+			return fmt.Errorf("reading %#v: %#v", filename, err)
+		}
+		var a AppManifest
+		if err := yaml.Unmarshal(b, &a); err != nil {
+			return fmt.Errorf("unmarshalling %#v: %#v", filename, err)
+		}
+		m.Apps[app.id] = m.Apps[app.id].overlay(a)
+	}
+	return nil
+}
+
+// overlay returns a with the settings made in o
+func (a AppManifest) overlay(o AppManifest) AppManifest {
+	layers := make(map[string][]string)
+	for name, globs := range a.Layers {
+		layers[name] = globs
+	}
+	for name, globs := range o.Layers {
+		layers[name] = globs
+	}
+	a.Layers = layers
+	if len(o.Slugs) > 0 {
+		a.Slugs = o.Slugs
+	}
+	if o.Merge != nil {
+		a.Merge = o.Merge
+	}
+	if o.OutFormat != "" {
+		a.OutFormat = o.OutFormat
+	}
+	if o.Extends != "" {
+		a.Extends = o.Extends
+	}
+	return a
+}
+
+// AppHierarchy returns the hierarchy of app: its own layers are added to
+// (or replace) the top-level layers while its slugs and merge options
+// replace the top-level ones when set
+func (m Manifest) AppHierarchy(app string) Hierarchy {
+	h := Hierarchy{
+		Layers: make(map[string][]string),
+		Slugs:  m.Slugs,
+		Merge:  m.Merge,
+	}
+	for name, globs := range m.Layers {
+		h.Layers[name] = globs
+	}
+
+	a := m.Apps[app]
+	for name, globs := range a.Layers {
+		h.Layers[name] = globs
+	}
+	if len(a.Slugs) > 0 {
+		h.Slugs = a.Slugs
+	}
+	if a.Merge != nil {
+		h.Merge = a.Merge
+	}
+	return h
+}
+
+// apply configures c with the merge options
+func (m *MergeManifest) apply(c *v1.Config) (*v1.Config, error) {
+	if m == nil {
+		return c, nil
+	}
+	c = c.WithOverwriteArrays(m.OverwriteArrays).
+		WithKeepArrayDuplicates(m.KeepArrayDuplicates).
+		WithSortMergedArrays(m.SortMergedArrays).
+		WithDeleteNilValues(m.DeleteNilValues)
+	if m.KnockoutPrefix != "" {
+		c = c.WithKnockout(m.KnockoutPrefix)
+	}
+	for _, s := range m.Strategies {
+		strategy, err := v1.ParseStrategy(s.Strategy)
+		if err != nil {
+			return nil, fmt.Errorf("%s: merge strategy for %#v: %v", ManifestFile, s.Path, err)
+		}
+		c = c.WithPathStrategy(s.Path, strategy)
+	}
+	return c, nil
+}
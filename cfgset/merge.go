@@ -0,0 +1,535 @@
+package cfgset
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/codec"
+	"github.com/davidalpert/go-deep-merge/v1"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MergeOptions configures Merge
+type MergeOptions struct {
+	// SourceFolder is the folder which holds one folder per app
+	SourceFolder string
+	// Debug set to true to write a trace of each merge to stdout
+	Debug bool
+	// PreserveFormatting set to true to also merge each slug as a yaml.Node which keeps comments, key order and style (see MergeResult.NodeBySlug)
+	PreserveFormatting bool
+}
+
+// Merge merges a source folder of config files grouped by app
+// assuming that each app folder contains a default.yaml and one or more
+// slug.yaml (e.g. dev.yaml, prd.yaml, etc)
+//
+// app folders can be nested at any depth (e.g. team-a/payments/api): any
+// folder holding a default file or a goconfig.yaml manifest is an app, as
+// is each folder directly in the source folder which holds config files;
+// each app is identified by its path relative to the source folder
+//
+// folders named with a leading _ are not apps so they can hold the files
+// shared through layers or includes (e.g. _shared/logging.yaml, see
+// IncludeKey)
+//
+// files can be written in any format supported by the codec package (e.g.
+// default.yaml, dev.json, prd.toml) and formats can be mixed within an app
+// folder, but each slug must have only one file
+//
+// a goconfig.yaml manifest in the source folder can declare the layers
+// merged for each slug instead (see Manifest)
+//
+// an app can extend another app (see AppManifest.Extends): each slug of
+// the parent app is merged first, then the files of the child app are
+// merged on top; a slug the parent app lacks falls back to its nearest
+// dotted ancestor (e.g. dev for dev.blue) or its default files, and the
+// child app has every slug of its parent
+//
+// the files in a _global folder in the source folder are shared by every
+// app: for each slug the _global file of each of its layers (e.g.
+// _global/default.yaml and _global/prd.yaml) is merged underneath the
+// files of the app, which can then leave out layers provided by _global
+func Merge(fs afero.Fs, o MergeOptions) ([]MergeResult, error) {
+	manifest, err := ReadManifest(fs, o.SourceFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	global := make(map[string]string)
+	globalDir := path.Join(o.SourceFolder, GlobalFolder)
+	if ok, _ := afero.DirExists(fs, globalDir); ok {
+		if global, err = appFiles(fs, globalDir); err != nil {
+			return nil, err
+		}
+	}
+
+	apps, err := appFolders(fs, o.SourceFolder, manifest.Apps)
+	if err != nil {
+		return nil, err
+	}
+
+	m := appMerger{
+		fs:       fs,
+		o:        o,
+		manifest: manifest,
+		global:   sharedLayers{dir: globalDir, fileBySlug: global},
+		apps:     make(map[string]appFolder),
+		results:  make(map[string]*MergeResult),
+	}
+	for _, app := range apps {
+		m.apps[app.id] = app
+	}
+
+	result := make([]MergeResult, 0)
+	for _, app := range apps {
+		r, err := m.merge(app.id, nil)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *r)
+	}
+	return result, nil
+}
+
+// appFolder is an app folder and the id of its app
+type appFolder struct {
+	// id is the path of the folder relative to the source folder (e.g. team-a/payments/api)
+	id  string
+	dir string
+}
+
+// appFolders returns the app folders found at any depth in sourceFolder:
+// a folder is an app when it holds a default file or a manifest or is
+// named under apps in the manifest of sourceFolder, and each folder
+// directly in sourceFolder is an app when it holds any config file; other
+// folders only group apps, and folders named with a leading _ (such as
+// _global) hold shared files rather than apps
+func appFolders(fs afero.Fs, sourceFolder string, declared map[string]AppManifest) ([]appFolder, error) {
+	result := make([]appFolder, 0)
+	var walk func(dir string, id string) error
+	walk = func(dir string, id string) error {
+		fis, err := afero.ReadDir(fs, dir)
+		if err != nil {
+			return fmt.Errorf("reading source folder %#v: %#v", dir, err)
+		}
+
+		if id != "" {
+			_, isApp := declared[id]
+			for _, fi := range fis {
+				if fi.IsDir() {
+					continue
+				}
+				isConfigFile := codec.IsConfigFile(fi.Name())
+				if fi.Name() == ManifestFile || (isConfigFile && codec.TrimExt(fi.Name()) == "default") || (isConfigFile && !strings.Contains(id, "/")) {
+					isApp = true
+				}
+			}
+			if isApp {
+				result = append(result, appFolder{id: id, dir: dir})
+			}
+		}
+
+		for _, fi := range fis {
+			if fi.IsDir() && !strings.HasPrefix(fi.Name(), "_") {
+				if err := walk(path.Join(dir, fi.Name()), path.Join(id, fi.Name())); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return result, walk(sourceFolder, "")
+}
+
+// GlobalFolder is the name of the folder in a source folder which holds the layers shared by every app
+const GlobalFolder = "_global"
+
+// sharedLayers holds the name of the file for each layer shared by every app
+type sharedLayers struct {
+	dir        string
+	fileBySlug map[string]string
+}
+
+// appMerger merges each app once, after the app it extends
+type appMerger struct {
+	fs       afero.Fs
+	o        MergeOptions
+	manifest Manifest
+	global   sharedLayers
+	apps     map[string]appFolder
+	results  map[string]*MergeResult
+}
+
+// merge merges app; stack holds the apps which extend it and are waiting on it
+func (m *appMerger) merge(app string, stack []string) (*MergeResult, error) {
+	if r, ok := m.results[app]; ok {
+		return r, nil
+	}
+	for i, a := range stack {
+		if a == app {
+			return nil, fmt.Errorf("app %#v extends itself: %s", app, strings.Join(append(stack[i:], app), " -> "))
+		}
+	}
+
+	var parent *MergeResult
+	if extends := m.manifest.Apps[app].Extends; extends != "" {
+		if _, ok := m.apps[extends]; !ok {
+			return nil, fmt.Errorf("app %#v extends %#v which is not an app folder in %#v", app, extends, m.o.SourceFolder)
+		}
+		var err error
+		if parent, err = m.merge(extends, append(stack, app)); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := mergeApp(m.fs, m.apps[app], m.manifest.AppHierarchy(app), m.global, parent, m.o)
+	if err != nil {
+		return nil, err
+	}
+	m.results[app] = &r
+	return &r, nil
+}
+
+// mergeApp merges the layers of each slug of app on top of the files
+// merged for the same slug of the parent app it extends, if any
+func mergeApp(fs afero.Fs, app appFolder, h Hierarchy, global sharedLayers, parent *MergeResult, o MergeOptions) (MergeResult, error) {
+	appDir := app.dir
+	r := MergeResult{
+		AppDir:           app.id,
+		MergeBySlug:      make(map[string]map[string]interface{}),
+		ProvenanceBySlug: make(map[string]v1.Provenance),
+		FilesBySlug:      make(map[string][]string),
+		IncludesBySlug:   make(map[string][]Include),
+		NodeBySlug:       make(map[string]*yaml.Node),
+	}
+
+	fileBySlug, err := appFiles(fs, appDir)
+	if err != nil {
+		return r, err
+	}
+
+	// the layers provided from outside the app folder
+	inherited := make(map[string]bool)
+	for layer := range global.fileBySlug {
+		inherited[layer] = true
+	}
+	if parent != nil {
+		r.Extends = parent.AppDir
+		inherited["default"] = true
+		for slug := range parent.MergeBySlug {
+			inherited[slug] = true
+		}
+	}
+
+	layersBySlug, err := h.layersBySlug(appDir, fileBySlug, inherited, parent)
+	if err != nil {
+		return r, err
+	}
+
+	// files merged in order for a list of layers; those the app does not provide come from _global or the parent app
+	layerFiles := func(slug string, layers []string) ([]string, error) {
+		files := make([]string, 0, len(layers))
+		for _, layer := range layers {
+			if name, ok := global.fileBySlug[layer]; ok {
+				files = append(files, path.Join(global.dir, name))
+			}
+		}
+		if parent != nil {
+			files = append(files, parent.inheritedFiles(slug)...)
+		}
+		for _, layer := range layers {
+			if !h.hasLayer(layer, fileBySlug) {
+				continue
+			}
+			lf, err := h.layerFiles(fs, appDir, layer, fileBySlug)
+			if err != nil {
+				return nil, fmt.Errorf("slug %#v: %v", slug, err)
+			}
+			files = append(files, lf...)
+		}
+		return uniqueFiles(files), nil
+	}
+
+	if r.defaultFiles, err = layerFiles("default", []string{"default"}); err != nil {
+		return r, err
+	}
+	for slug, layers := range layersBySlug {
+		files, err := layerFiles(slug, layers)
+		if err != nil {
+			return r, err
+		}
+		if err := mergeFiles(fs, files, h, o, slug, &r); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// uniqueFiles returns files without the repeats of a file, which is merged where it is first listed
+func uniqueFiles(files []string) []string {
+	unique := make([]string, 0, len(files))
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if !seen[f] {
+			seen[f] = true
+			unique = append(unique, f)
+		}
+	}
+	return unique
+}
+
+// inheritedFiles returns the files merged for slug by an app which extends
+// r: those r merged for slug or else for the nearest dotted ancestor of
+// slug which r has, or else its default files
+func (r *MergeResult) inheritedFiles(slug string) []string {
+	for {
+		if files, ok := r.FilesBySlug[slug]; ok {
+			return files
+		}
+		i := strings.LastIndex(slug, ".")
+		if i <= 0 {
+			return r.defaultFiles
+		}
+		slug = slug[:i]
+	}
+}
+
+// appFiles returns the name of the config file for each slug in appDir
+func appFiles(fs afero.Fs, appDir string) (map[string]string, error) {
+	fis, err := afero.ReadDir(fs, appDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading app folder %#v: %#v", appDir, err)
+	}
+
+	fileBySlug := make(map[string]string)
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() || name == ManifestFile || !codec.IsConfigFile(name) {
+			continue
+		}
+		slug := codec.TrimExt(name)
+		if other, ok := fileBySlug[slug]; ok {
+			return nil, fmt.Errorf("found more than one file for %#v in %#v: %#v and %#v", slug, appDir, other, name)
+		}
+		fileBySlug[slug] = name
+	}
+	return fileBySlug, nil
+}
+
+// layersBySlug returns the layers merged for each slug, resolving each
+// slug named among the layers of another to its own layers
+//
+// slugs are those declared by the hierarchy or, by convention, one per
+// file other than default; by convention each slug inherits from its
+// nearest existing dotted ancestor (e.g. dev.us-east-1.blue from
+// dev.us-east-1, or dev when there is no dev.us-east-1) and a slug without
+// one inherits from default
+func (h Hierarchy) layersBySlug(appDir string, fileBySlug map[string]string, inherited map[string]bool, parent *MergeResult) (map[string][]string, error) {
+	declared := h.Slugs
+	if len(declared) == 0 {
+		if !h.hasLayer("default", fileBySlug) && !inherited["default"] {
+			return nil, fmt.Errorf("app folder %#v has no default file (e.g. default.yaml)", appDir)
+		}
+
+		declared = make(map[string][]string)
+		for slug := range fileBySlug {
+			if slug != "default" {
+				declared[slug] = []string{"default", dottedParent(slug, fileBySlug, inherited), slug}
+			}
+		}
+		if parent != nil {
+			// an app which extends another has each of its slugs
+			for slug := range parent.MergeBySlug {
+				if _, ok := declared[slug]; !ok {
+					declared[slug] = []string{"default", dottedParent(slug, fileBySlug, inherited), slug}
+				}
+			}
+		}
+	}
+
+	slugs := make([]string, 0, len(declared))
+	for slug := range declared {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	r := slugResolver{appDir: appDir, h: h, declared: declared, fileBySlug: fileBySlug, inherited: inherited, resolved: make(map[string][]string)}
+	for _, slug := range slugs {
+		if _, err := r.resolve(slug, nil); err != nil {
+			return nil, err
+		}
+	}
+	return r.resolved, nil
+}
+
+// dottedParent returns the nearest ancestor of slug which has a file in
+// the app or is inherited (e.g. dev.us-east-1 or dev for
+// dev.us-east-1.blue) or default
+func dottedParent(slug string, fileBySlug map[string]string, inherited map[string]bool) string {
+	for i := strings.LastIndex(slug, "."); i > 0; i = strings.LastIndex(slug, ".") {
+		slug = slug[:i]
+		if fileBySlug[slug] != "" || inherited[slug] {
+			return slug
+		}
+	}
+	return "default"
+}
+
+type slugResolver struct {
+	appDir     string
+	h          Hierarchy
+	declared   map[string][]string
+	fileBySlug map[string]string
+	inherited  map[string]bool
+	resolved   map[string][]string
+}
+
+// resolve returns the layers of slug with every other slug among them
+// replaced by its own layers; stack holds the slugs being resolved
+func (r *slugResolver) resolve(slug string, stack []string) ([]string, error) {
+	if layers, ok := r.resolved[slug]; ok {
+		return layers, nil
+	}
+	for i, s := range stack {
+		if s == slug {
+			return nil, fmt.Errorf("slug %#v inherits from itself: %s", slug, strings.Join(append(stack[i:], slug), " -> "))
+		}
+	}
+	stack = append(stack, slug)
+
+	if len(r.declared[slug]) == 0 {
+		return nil, fmt.Errorf("%s: slug %#v has no layers", ManifestFile, slug)
+	}
+
+	layers := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(layer string) {
+		// a layer shared by two parents is merged once, where it is first used
+		if !seen[layer] {
+			seen[layer] = true
+			layers = append(layers, layer)
+		}
+	}
+	for _, name := range r.declared[slug] {
+		if _, isSlug := r.declared[name]; isSlug && name != slug {
+			parentLayers, err := r.resolve(name, stack)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range parentLayers {
+				add(l)
+			}
+			continue
+		}
+		if !r.h.hasLayer(name, r.fileBySlug) && !r.inherited[name] {
+			return nil, fmt.Errorf("slug %#v: parent %#v is not a slug, a layer or a file in %#v", slug, name, r.appDir)
+		}
+		add(name)
+	}
+
+	r.resolved[slug] = layers
+	return layers, nil
+}
+
+// hasLayer returns true when the app declares layer or has a file for it
+func (h Hierarchy) hasLayer(layer string, fileBySlug map[string]string) bool {
+	_, isLayer := h.Layers[layer]
+	_, isFile := fileBySlug[layer]
+	return isLayer || isFile
+}
+
+// layerFiles returns the files merged in order for layer: the files
+// matching its globs (sorted within each glob) or the file named by layer
+func (h Hierarchy) layerFiles(fs afero.Fs, appDir string, layer string, fileBySlug map[string]string) ([]string, error) {
+	globs, ok := h.Layers[layer]
+	if !ok {
+		name, ok := fileBySlug[layer]
+		if !ok {
+			return nil, fmt.Errorf("app folder %#v has no file for layer %#v", appDir, layer)
+		}
+		return []string{path.Join(appDir, name)}, nil
+	}
+
+	files := make([]string, 0)
+	for _, glob := range globs {
+		matches, err := afero.Glob(fs, path.Join(appDir, glob))
+		if err != nil {
+			return nil, fmt.Errorf("layer %#v: %v", layer, err)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if codec.IsConfigFile(m) {
+				files = append(files, m)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("layer %#v matches no files in %#v", layer, appDir)
+	}
+	return files, nil
+}
+
+// mergeFiles merges each file onto the merge of the files before it and
+// records the result for slug in r
+func mergeFiles(fs afero.Fs, files []string, h Hierarchy, o MergeOptions, slug string, r *MergeResult) error {
+	// parse the files together so each one can reference the anchors of the files it is merged onto
+	layers, err := decodeLayers(fs, files)
+	if err != nil {
+		return err
+	}
+
+	includeCfg, err := h.Merge.apply(v1.NewConfigDeeperMergeBang().WithMergeHashArrays(true).WithDeepMergeKeys(true))
+	if err != nil {
+		return err
+	}
+	origins, includes, err := expandIncludes(fs, layers, files, includeCfg)
+	if err != nil {
+		return err
+	}
+
+	dest, err := decodeLayer(layers[0], files[0])
+	if err != nil {
+		return err
+	}
+	destNode := layers[0]
+	provenance := v1.NewProvenance().Seed(files[0], dest)
+	attributeIncludes(provenance, files[0], origins[0])
+
+	for i := 1; i < len(files); i++ {
+		src, err := decodeLayer(layers[i], files[i])
+		if err != nil {
+			return err
+		}
+
+		cfg, err := h.Merge.apply(v1.NewConfigDeeperMergeBang().WithMergeHashArrays(true).WithDebug(o.Debug).WithProvenance(files[i], provenance))
+		if err != nil {
+			return err
+		}
+		if dest, err = v1.MergeCopyWithOptions(src, dest, cfg); err != nil {
+			return fmt.Errorf("merging files %#v -> %#v: %#v", files[i], files[0], err)
+		}
+		attributeIncludes(provenance, files[i], origins[i])
+
+		if o.PreserveFormatting && layers[i] != nil {
+			if destNode == nil {
+				destNode = layers[i]
+				continue
+			}
+			cfg, _ := h.Merge.apply(v1.NewConfigDeeperMergeBang().WithMergeHashArrays(true).WithDeepMergeKeys(true))
+			if destNode, err = v1.MergeNodesWithOptions(layers[i], destNode, cfg); err != nil {
+				return fmt.Errorf("merging files %#v -> %#v: %#v", files[i], files[0], err)
+			}
+		}
+	}
+
+	r.MergeBySlug[slug] = dest
+	r.ProvenanceBySlug[slug] = provenance
+	r.FilesBySlug[slug] = files
+	r.IncludesBySlug[slug] = includes
+	if o.PreserveFormatting && destNode != nil {
+		r.NodeBySlug[slug] = destNode
+	}
+	return nil
+}
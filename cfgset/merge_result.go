@@ -0,0 +1,91 @@
+package cfgset
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/v1"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+)
+
+// MergeResult holds the merged config of one app by slug
+type MergeResult struct {
+	// AppDir identifies the app by the path of its folder relative to the source folder (e.g. app1 or team-a/payments/api)
+	AppDir string
+	// MergeBySlug holds the merged config for each slug
+	MergeBySlug map[string]map[string]interface{}
+	// ProvenanceBySlug records which file last wrote each key path of the merge for each slug; a value
+	// which came from an included file names the chain of files it was included through (see IncludeSeparator)
+	ProvenanceBySlug map[string]v1.Provenance
+	// FilesBySlug lists the files merged together for each slug in the order they were merged
+	FilesBySlug map[string][]string
+	// IncludesBySlug lists the files included by the files merged for each slug (see IncludeKey and IncludeTag)
+	IncludesBySlug map[string][]Include
+	// Extends names the app this app extends, if any
+	Extends string
+	// NodeBySlug holds the merge for each slug as a YAML document which keeps the comments, key order and style of the merged files (see MergeOptions.PreserveFormatting)
+	NodeBySlug map[string]*yaml.Node
+
+	// defaultFiles lists the files merged for the default layer, which an app extending this one inherits
+	defaultFiles []string
+}
+
+var (
+	// DefaultPathSeparator joins the keys of flattened paths
+	DefaultPathSeparator = "/"
+	// DefaultValueSeparator separates each flattened path from its value in FlattenedToString
+	DefaultValueSeparator = ":"
+)
+
+// FlattenToMap returns every value of every slug by a path made of the app,
+// the slug and the keys and indexes leading to it (e.g. app1/dev/db/host
+// or team-a/payments/api/dev/db/host)
+func (r *MergeResult) FlattenToMap() map[string]string {
+	return r.FlattenToMapWithSep(DefaultPathSeparator)
+}
+
+// FlattenToMapWithSep is FlattenToMap with paths (including the folders of
+// the app) joined by sep
+func (r *MergeResult) FlattenToMapWithSep(sep string) map[string]string {
+	result := make(map[string]string)
+	app := strings.ReplaceAll(r.AppDir, "/", sep)
+	for slug, merge := range r.MergeBySlug {
+		for k, v := range RecursiveFlattenToMapWithSep(app+sep+slug, merge, sep) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// FlattenedToString writes one "path: value" line per flattened value, in sorted order
+func FlattenedToString(flattened map[string]string) string {
+	strResult := make([]string, 0)
+	for k, v := range flattened {
+		strResult = append(strResult, k+DefaultValueSeparator+" "+v)
+	}
+	sort.Strings(strResult)
+	return strings.Join(strResult, "\n") + "\n"
+}
+
+// RecursiveFlattenToMapWithSep returns every scalar below v formatted as a
+// string by prefix joined by sep to the keys and indexes leading to it
+func RecursiveFlattenToMapWithSep(prefix string, v interface{}, sep string) map[string]string {
+	result := make(map[string]string)
+	switch vv := v.(type) {
+	case []interface{}:
+		for i, vvv := range vv {
+			for ik, iv := range RecursiveFlattenToMapWithSep(prefix+sep+fmt.Sprintf("%d", i), vvv, sep) {
+				result[ik] = iv
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range vv {
+			for ik, iv := range RecursiveFlattenToMapWithSep(prefix+sep+k, v, sep) {
+				result[ik] = iv
+			}
+		}
+	default:
+		result[prefix] = fmt.Sprintf("%v", vv)
+	}
+	return result
+}
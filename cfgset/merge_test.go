@@ -0,0 +1,424 @@
+package cfgset
+
+import (
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"os"
+	"testing"
+)
+
+func newFs(t *testing.T, files map[string]string) afero.Fs {
+	fs := afero.NewMemMapFs()
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), os.ModePerm); err != nil {
+			assert.FailNow(t, "write file", "%s: %v", name, err)
+		}
+	}
+	return fs
+}
+
+func TestMerge(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/app1/default.yaml":       "env: REQUIRED\nlog_level: WARN\ndb:\n  host: localhost\n  port: 5432\n",
+		"config/app1/dev.yaml":           "env: dev\nlog_level: DEBUG\n",
+		"config/app1/dev.us-east-1.yaml": "db:\n  host: us-east-1-db\n",
+		"config/app1/prd.json":           `{"env": "prd", "db": {"port": 6543}}`,
+		"config/app2/default.toml":       "env = \"REQUIRED\"\n",
+		"config/app2/stg.env":            "env=stg\n",
+		"config/app2/notes.txt":          "not a config file",
+		"config/goconfig.yaml":           "apps: {}\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config"})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 2) {
+		return
+	}
+
+	app1 := results[0]
+	assert.Equal(t, "app1", app1.AppDir)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"dev":           {"env": "dev", "log_level": "DEBUG", "db": map[string]interface{}{"host": "localhost", "port": 5432}},
+		"dev.us-east-1": {"env": "dev", "log_level": "DEBUG", "db": map[string]interface{}{"host": "us-east-1-db", "port": 5432}},
+		"prd":           {"env": "prd", "log_level": "WARN", "db": map[string]interface{}{"host": "localhost", "port": 6543}},
+	}, app1.MergeBySlug)
+	assert.Equal(t, []string{"config/app1/default.yaml", "config/app1/dev.yaml", "config/app1/dev.us-east-1.yaml"}, app1.FilesBySlug["dev.us-east-1"])
+	assert.Empty(t, app1.NodeBySlug)
+
+	app2 := results[1]
+	assert.Equal(t, "app2", app2.AppDir)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"stg": {"env": "stg"},
+	}, app2.MergeBySlug)
+
+	assert.Equal(t, "app2/stg/env: stg\n", FlattenedToString(app2.FlattenToMap()))
+}
+
+func TestMergePreserveFormatting(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/app1/default.yaml": "# defaults\nenv: REQUIRED\nlog_level: WARN\n",
+		"config/app1/dev.yaml":     "env: dev\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config", PreserveFormatting: true})
+	if assert.NoError(t, err) && assert.Len(t, results, 1) {
+		b, err := yaml.Marshal(results[0].NodeBySlug["dev"])
+		if assert.NoError(t, err) {
+			assert.Equal(t, "# defaults\nenv: dev\nlog_level: WARN\n", string(b))
+		}
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "missing default",
+			files: map[string]string{"config/app1/dev.yaml": "env: dev\n"},
+			want:  `app folder "config/app1" has no default file (e.g. default.yaml)`,
+		},
+		{
+			name:  "two files for one slug",
+			files: map[string]string{"config/app1/default.yaml": "a: 1\n", "config/app1/dev.json": "{}", "config/app1/dev.yaml": "a: 2\n"},
+			want:  `found more than one file for "dev" in "config/app1": "dev.json" and "dev.yaml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Merge(newFs(t, tt.files), MergeOptions{SourceFolder: "config"})
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	m, err := ReadManifest(newFs(t, map[string]string{}), "config")
+	if assert.NoError(t, err) {
+		assert.Empty(t, m.Apps)
+	}
+
+	m, err = ReadManifest(newFs(t, map[string]string{"config/goconfig.yaml": "apps:\n  app1:\n    out_format: json\n"}), "config")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "json", m.Apps["app1"].OutFormat)
+	}
+}
+
+func TestMergeDeepSlugs(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/app1/default.yaml":                "a: default\nb: default\nc: default\nd: default\n",
+		"config/app1/dev.yaml":                    "b: dev\n",
+		"config/app1/dev.us-east-1.yaml":          "c: dev.us-east-1\n",
+		"config/app1/dev.us-east-1.blue.yaml":     "d: dev.us-east-1.blue\n",
+		"config/app1/dev.eu-west-1.green.yaml":    "d: dev.eu-west-1.green\n",
+		"config/app1/stg.us-east-1.yaml":          "c: stg.us-east-1\n",
+		"config/app1/dev.us-east-1.blue.a.b.yaml": "a: dev.us-east-1.blue.a.b\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config"})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 1) {
+		return
+	}
+
+	assert.Equal(t, map[string]interface{}{"a": "dev.us-east-1.blue.a.b", "b": "dev", "c": "dev.us-east-1", "d": "dev.us-east-1.blue"}, results[0].MergeBySlug["dev.us-east-1.blue.a.b"])
+	assert.Equal(t, []string{
+		"config/app1/default.yaml",
+		"config/app1/dev.yaml",
+		"config/app1/dev.us-east-1.yaml",
+		"config/app1/dev.us-east-1.blue.yaml",
+		"config/app1/dev.us-east-1.blue.a.b.yaml",
+	}, results[0].FilesBySlug["dev.us-east-1.blue.a.b"])
+	assert.Equal(t, []string{"config/app1/default.yaml", "config/app1/dev.yaml", "config/app1/dev.eu-west-1.green.yaml"}, results[0].FilesBySlug["dev.eu-west-1.green"])
+	assert.Equal(t, []string{"config/app1/default.yaml", "config/app1/stg.us-east-1.yaml"}, results[0].FilesBySlug["stg.us-east-1"])
+}
+
+func TestMergeManifest(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/goconfig.yaml": `
+layers:
+  default: [default.yaml, "defaults/*.yaml"]
+slugs:
+  prd: [default, prd]
+  prd-eu: [prd, eu]
+  qa: [default, dev, qa]
+merge:
+  overwrite_arrays: true
+apps:
+  app2:
+    slugs:
+      dev: [default, dev]
+    merge:
+      overwrite_arrays: false
+`,
+		"config/app1/default.yaml":         "env: REQUIRED\nregions: [us]\n",
+		"config/app1/defaults/b.yaml":      "log_level: WARN\n",
+		"config/app1/defaults/a.yaml":      "log_level: INFO\ntimeout: 30\n",
+		"config/app1/dev.yaml":             "env: dev\n",
+		"config/app1/qa.yaml":              "log_level: DEBUG\n",
+		"config/app1/prd.yaml":             "env: prd\n",
+		"config/app1/eu.yaml":              "regions: [eu-west-1]\n",
+		"config/app2/default.yaml":         "regions: [us]\n",
+		"config/app2/defaults/common.yaml": "timeout: 10\n",
+		"config/app2/dev.yaml":             "regions: [eu]\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config"})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 2) {
+		return
+	}
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"prd":    {"env": "prd", "log_level": "WARN", "timeout": 30, "regions": []interface{}{"us"}},
+		"prd-eu": {"env": "prd", "log_level": "WARN", "timeout": 30, "regions": []interface{}{"eu-west-1"}},
+		"qa":     {"env": "dev", "log_level": "DEBUG", "timeout": 30, "regions": []interface{}{"us"}},
+	}, results[0].MergeBySlug)
+	assert.Equal(t, []string{
+		"config/app1/default.yaml",
+		"config/app1/defaults/a.yaml",
+		"config/app1/defaults/b.yaml",
+		"config/app1/prd.yaml",
+		"config/app1/eu.yaml",
+	}, results[0].FilesBySlug["prd-eu"])
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"dev": {"timeout": 10, "regions": []interface{}{"us", "eu"}},
+	}, results[1].MergeBySlug)
+}
+
+func TestMergeManifestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{
+			name:     "missing layer file",
+			manifest: "slugs:\n  dev: [default, missing]\n",
+			want:     `slug "dev": parent "missing" is not a slug, a layer or a file in "config/app1"`,
+		},
+		{
+			name:     "inheritance cycle",
+			manifest: "slugs:\n  a: [default, c]\n  b: [a, dev]\n  c: [b]\n",
+			want:     `slug "a" inherits from itself: a -> c -> b -> a`,
+		},
+		{
+			name:     "glob matches nothing",
+			manifest: "layers:\n  extra: [\"extra/*.yaml\"]\nslugs:\n  dev: [default, extra]\n",
+			want:     `slug "dev": layer "extra" matches no files in "config/app1"`,
+		},
+		{
+			name:     "unknown strategy",
+			manifest: "merge:\n  strategies:\n    - path: a\n      strategy: shuffle\n",
+			want:     `goconfig.yaml: merge strategy for "a": unrecognized merge strategy "shuffle": supported strategies are: replace, union, union-sorted, append, merge-by-index, merge-by-key:<field>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFs(t, map[string]string{
+				"config/goconfig.yaml":     tt.manifest,
+				"config/app1/default.yaml": "a: 1\n",
+				"config/app1/dev.yaml":     "a: 2\n",
+			})
+			_, err := Merge(fs, MergeOptions{SourceFolder: "config"})
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestMergeGlobal(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/_global/default.yaml":      "logging:\n  endpoint: http://logs.local\n  level: INFO\n",
+		"config/_global/prd.yaml":          "logging:\n  endpoint: https://logs.example.com\n",
+		"config/_global/dev.yaml":          "logging:\n  level: DEBUG\n",
+		"config/_global/stg.yaml":          "logging:\n  level: WARN\n",
+		"config/app1/default.yaml":         "name: app1\nlogging:\n  level: WARN\n",
+		"config/app1/prd.yaml":             "replicas: 3\n",
+		"config/app2/dev.us-east-1.yaml":   "region: us-east-1\n",
+		"config/app2/prd.yaml":             "name: app2\n",
+		"config/app2/prd.eu-west-1.yaml":   "region: eu-west-1\n",
+		"config/app2/prd.eu-west-1.a.yaml": "zone: a\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config"})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 2) {
+		return
+	}
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"prd": {"name": "app1", "replicas": 3, "logging": map[string]interface{}{"endpoint": "https://logs.example.com", "level": "WARN"}},
+	}, results[0].MergeBySlug)
+	assert.Equal(t, []string{"config/_global/default.yaml", "config/_global/prd.yaml", "config/app1/default.yaml", "config/app1/prd.yaml"}, results[0].FilesBySlug["prd"])
+
+	assert.Equal(t, map[string]interface{}{"region": "us-east-1", "logging": map[string]interface{}{"endpoint": "http://logs.local", "level": "DEBUG"}}, results[1].MergeBySlug["dev.us-east-1"])
+	assert.Equal(t, []string{"config/_global/default.yaml", "config/_global/dev.yaml", "config/app2/dev.us-east-1.yaml"}, results[1].FilesBySlug["dev.us-east-1"])
+	assert.Equal(t, []string{
+		"config/_global/default.yaml",
+		"config/_global/prd.yaml",
+		"config/app2/prd.yaml",
+		"config/app2/prd.eu-west-1.yaml",
+		"config/app2/prd.eu-west-1.a.yaml",
+	}, results[1].FilesBySlug["prd.eu-west-1.a"])
+	assert.NotContains(t, results[1].MergeBySlug, "stg")
+}
+
+func TestMergeExtends(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/goconfig.yaml":               "apps:\n  worker-high:\n    extends: worker\n",
+		"config/worker/default.yaml":         "name: worker\nreplicas: 1\nqueues: [default]\n",
+		"config/worker/dev.yaml":             "log_level: DEBUG\n",
+		"config/worker/prd.yaml":             "replicas: 2\n",
+		"config/worker-high/default.yaml":    "name: worker-high\nqueues: [high]\n",
+		"config/worker-high/prd.yaml":        "replicas: 5\n",
+		"config/worker-high/prd.eu.yaml":     "region: eu\n",
+		"config/worker-high/stg.yaml":        "log_level: INFO\n",
+		"config/worker-low/goconfig.yaml":    "extends: worker-high\n",
+		"config/worker-low/prd.yaml":         "replicas: 1\n",
+		"config/worker-low/dev.us.blue.yaml": "region: us\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config"})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 3) {
+		return
+	}
+
+	high := results[1]
+	assert.Equal(t, "worker", high.Extends)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"dev":    {"name": "worker-high", "replicas": 1, "queues": []interface{}{"default", "high"}, "log_level": "DEBUG"},
+		"prd":    {"name": "worker-high", "replicas": 5, "queues": []interface{}{"default", "high"}},
+		"prd.eu": {"name": "worker-high", "replicas": 5, "queues": []interface{}{"default", "high"}, "region": "eu"},
+		"stg":    {"name": "worker-high", "replicas": 1, "queues": []interface{}{"default", "high"}, "log_level": "INFO"},
+	}, high.MergeBySlug)
+	assert.Equal(t, []string{
+		"config/worker/default.yaml",
+		"config/worker/prd.yaml",
+		"config/worker-high/default.yaml",
+		"config/worker-high/prd.yaml",
+		"config/worker-high/prd.eu.yaml",
+	}, high.FilesBySlug["prd.eu"])
+
+	low := results[2]
+	assert.Equal(t, "worker-high", low.Extends)
+	assert.Len(t, low.MergeBySlug, 5)
+	assert.Equal(t, []string{
+		"config/worker/default.yaml",
+		"config/worker/dev.yaml",
+		"config/worker-high/default.yaml",
+		"config/worker-low/dev.us.blue.yaml",
+	}, low.FilesBySlug["dev.us.blue"])
+	assert.Equal(t, 1, low.MergeBySlug["prd.eu"]["replicas"])
+	assert.Equal(t, "eu", low.MergeBySlug["prd.eu"]["region"])
+}
+
+func TestMergeExtendsErrors(t *testing.T) {
+	_, err := Merge(newFs(t, map[string]string{
+		"config/app1/goconfig.yaml": "extends: app2\n",
+		"config/app1/default.yaml":  "a: 1\n",
+		"config/app2/goconfig.yaml": "extends: app3\n",
+		"config/app2/default.yaml":  "a: 2\n",
+		"config/app3/goconfig.yaml": "extends: app1\n",
+		"config/app3/default.yaml":  "a: 3\n",
+	}), MergeOptions{SourceFolder: "config"})
+	assert.EqualError(t, err, `app "app1" extends itself: app1 -> app2 -> app3 -> app1`)
+
+	_, err = Merge(newFs(t, map[string]string{
+		"config/app1/goconfig.yaml": "extends: app9\n",
+		"config/app1/default.yaml":  "a: 1\n",
+	}), MergeOptions{SourceFolder: "config"})
+	assert.EqualError(t, err, `app "app1" extends "app9" which is not an app folder in "config"`)
+}
+
+func TestMergeNested(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/goconfig.yaml":                        "apps:\n  team-b/legacy:\n    layers:\n      default: [base.yaml]\n    slugs:\n      prd: [default, prd]\n",
+		"config/team-a/payments/api/default.yaml":     "name: api\nlog_level: WARN\n",
+		"config/team-a/payments/api/dev.yaml":         "log_level: DEBUG\n",
+		"config/team-a/payments/worker/goconfig.yaml": "extends: team-a/payments/api\n",
+		"config/team-a/payments/worker/prd.yaml":      "name: worker\n",
+		"config/team-a/payments/README.md":            "not a config file",
+		"config/team-b/legacy/base.yaml":              "name: legacy\n",
+		"config/team-b/legacy/prd.yaml":               "log_level: ERROR\n",
+		"config/team-b/legacy/defaults/extra.yaml":    "ignored: true\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config"})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 3) {
+		return
+	}
+
+	assert.Equal(t, "team-a/payments/api", results[0].AppDir)
+	assert.Equal(t, "team-a/payments/worker", results[1].AppDir)
+	assert.Equal(t, "team-a/payments/api", results[1].Extends)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"dev": {"name": "api", "log_level": "DEBUG"},
+		"prd": {"name": "worker", "log_level": "WARN"},
+	}, results[1].MergeBySlug)
+	assert.Equal(t, "team-b/legacy", results[2].AppDir)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"prd": {"name": "legacy", "log_level": "ERROR"},
+	}, results[2].MergeBySlug)
+
+	assert.Equal(t, "team-b/legacy/prd/log_level: ERROR\nteam-b/legacy/prd/name: legacy\n", FlattenedToString(results[2].FlattenToMap()))
+	assert.Equal(t, map[string]string{"team-b.legacy.prd.log_level": "ERROR", "team-b.legacy.prd.name": "legacy"}, results[2].FlattenToMapWithSep("."))
+}
+
+func TestMergeIncludes(t *testing.T) {
+	fs := newFs(t, map[string]string{
+		"config/_shared/logging.yaml":     "$include: levels/warn.yaml\nendpoint: http://logs.local\nformat: text\n",
+		"config/_shared/levels/warn.yaml": "level: WARN\n",
+		"config/_shared/metrics.json":     `{"port": 9090, "path": "/metrics"}`,
+		"config/app1/default.yaml":        "name: app1\nlogging:\n  $include: ../_shared/logging.yaml\n  format: json\nmetrics: !include ../_shared/metrics.json\n",
+		"config/app1/prd.yaml":            "logging:\n  endpoint: https://logs.example.com\n",
+	})
+
+	results, err := Merge(fs, MergeOptions{SourceFolder: "config", PreserveFormatting: true})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 1) {
+		return
+	}
+
+	app1 := results[0]
+	assert.Equal(t, map[string]interface{}{
+		"name":    "app1",
+		"logging": map[string]interface{}{"endpoint": "https://logs.example.com", "format": "json", "level": "WARN"},
+		"metrics": map[string]interface{}{"port": 9090, "path": "/metrics"},
+	}, app1.MergeBySlug["prd"])
+	assert.Equal(t, []string{"config/app1/default.yaml", "config/app1/prd.yaml"}, app1.FilesBySlug["prd"])
+	assert.Equal(t, []Include{
+		{File: "config/_shared/logging.yaml", IncludedBy: "config/app1/default.yaml", Path: "logging"},
+		{File: "config/_shared/levels/warn.yaml", IncludedBy: "config/_shared/logging.yaml", Path: "logging"},
+		{File: "config/_shared/metrics.json", IncludedBy: "config/app1/default.yaml", Path: "metrics"},
+	}, app1.IncludesBySlug["prd"])
+
+	provenance := app1.ProvenanceBySlug["prd"]
+	assert.Equal(t, "config/app1/default.yaml > config/_shared/logging.yaml > config/_shared/levels/warn.yaml", provenance["logging.level"].Source)
+	assert.Equal(t, "config/app1/default.yaml", provenance["logging.format"].Source)
+	assert.Equal(t, "config/app1/prd.yaml", provenance["logging.endpoint"].Source)
+	assert.Equal(t, "config/app1/default.yaml > config/_shared/logging.yaml", provenance["logging.endpoint"].Overrides[0].Source)
+	assert.Equal(t, "config/app1/default.yaml > config/_shared/metrics.json", provenance["metrics.port"].Source)
+
+	b, err := yaml.Marshal(app1.NodeBySlug["prd"])
+	if assert.NoError(t, err) {
+		assert.NotContains(t, string(b), IncludeKey)
+		assert.NotContains(t, string(b), IncludeTag)
+	}
+}
+
+func TestMergeIncludeErrors(t *testing.T) {
+	_, err := Merge(newFs(t, map[string]string{
+		"config/app1/default.yaml": "a:\n  $include: inc/a.yaml\n",
+		"config/app1/dev.yaml":     "c: 1\n",
+		"config/app1/inc/a.yaml":   "b: !include b.yaml\n",
+		"config/app1/inc/b.yaml":   "$include: a.yaml\n",
+	}), MergeOptions{SourceFolder: "config"})
+	assert.EqualError(t, err, `"config/app1/inc/a.yaml" includes itself: config/app1/inc/a.yaml > config/app1/inc/b.yaml > config/app1/inc/a.yaml`)
+
+	_, err = Merge(newFs(t, map[string]string{
+		"config/app1/default.yaml": "a:\n  $include: missing.yaml\n",
+		"config/app1/dev.yaml":     "c: 1\n",
+	}), MergeOptions{SourceFolder: "config"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `"config/app1/default.yaml" at "a": including "missing.yaml": &fs.PathError{Op:"open", Path:"config/app1/missing.yaml"`)
+	}
+}
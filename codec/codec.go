@@ -0,0 +1,272 @@
+// Package codec decodes config files into the map[string]interface{}
+// trees merged by the v1 package and encodes merged trees back into files
+//
+// each format is a Codec found by name or file extension in a registry
+// which comes with YAML, JSON, TOML, HCL, INI, dotenv and Java properties;
+// Register adds a format (or replaces one) for every goconfig command
+// and library function which reads or writes config files
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Codec decodes and encodes one config file format
+type Codec interface {
+	// Name is the name of the format (e.g. yaml)
+	Name() string
+	// Extensions lists the file extensions of the format (e.g. .yaml, .yml); files are written with the first
+	Extensions() []string
+	// Decode decodes a file into a map
+	Decode(b []byte) (map[string]interface{}, error)
+	// Encode encodes a map into a file with its keys in sorted order, or returns ErrNotSupported
+	Encode(m map[string]interface{}) ([]byte, error)
+}
+
+// ErrNotSupported is returned by the Encode method of a decode-only Codec
+var ErrNotSupported = errors.New("not supported")
+
+// DecodeFunc decodes a file into a map
+type DecodeFunc func(b []byte) (map[string]interface{}, error)
+
+// EncodeFunc encodes a map into a file
+type EncodeFunc func(m map[string]interface{}) ([]byte, error)
+
+// New returns a Codec made of functions; a nil encode makes it decode-only
+func New(name string, extensions []string, decode DecodeFunc, encode EncodeFunc) Codec {
+	return &funcCodec{name: name, extensions: extensions, decode: decode, encode: encode}
+}
+
+type funcCodec struct {
+	name       string
+	extensions []string
+	decode     DecodeFunc
+	encode     EncodeFunc
+}
+
+func (c *funcCodec) Name() string {
+	return c.name
+}
+
+func (c *funcCodec) Extensions() []string {
+	return c.extensions
+}
+
+func (c *funcCodec) Decode(b []byte) (map[string]interface{}, error) {
+	return c.decode(b)
+}
+
+func (c *funcCodec) Encode(m map[string]interface{}) ([]byte, error) {
+	if c.encode == nil {
+		return nil, fmt.Errorf("encoding %s: %w", c.name, ErrNotSupported)
+	}
+	return c.encode(m)
+}
+
+var (
+	YAML       = New("yaml", []string{".yaml", ".yml"}, decodeYAML, encodeYAML)
+	JSON       = New("json", []string{".json"}, decodeJSON, encodeJSON)
+	TOML       = New("toml", []string{".toml"}, decodeTOML, encodeTOML)
+	HCL        = New("hcl", []string{".hcl", ".tf"}, decodeHCL, nil)
+	INI        = New("ini", []string{".ini"}, decodeINI, nil)
+	Dotenv     = New("dotenv", []string{".env"}, decodeDotenv, encodeDotenv)
+	Properties = New("properties", []string{".properties"}, decodeProperties, encodeProperties)
+)
+
+var (
+	mu       sync.RWMutex
+	registry = []Codec{JSON, YAML, TOML, HCL, INI, Dotenv, Properties}
+)
+
+// Register adds c to the registry, replacing the codec with the same name;
+// c takes precedence over the codecs registered before it for its extensions
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, r := range registry {
+		if strings.EqualFold(r.Name(), c.Name()) {
+			registry = append(registry[:i], registry[i+1:]...)
+			break
+		}
+	}
+	registry = append(registry, c)
+}
+
+// Codecs lists the registered codecs in the order they were registered
+func Codecs() []Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Codec(nil), registry...)
+}
+
+// Names lists the names of the registered codecs
+func Names() []string {
+	cc := Codecs()
+	nn := make([]string, len(cc))
+	for i, c := range cc {
+		nn[i] = c.Name()
+	}
+	return nn
+}
+
+// EncoderNames lists the names of the registered codecs which can encode
+func EncoderNames() []string {
+	nn := make([]string, 0)
+	for _, c := range Codecs() {
+		if CanEncode(c) {
+			nn = append(nn, c.Name())
+		}
+	}
+	return nn
+}
+
+// CanEncode returns false when c is decode-only
+func CanEncode(c Codec) bool {
+	_, err := c.Encode(map[string]interface{}{})
+	return !errors.Is(err, ErrNotSupported)
+}
+
+// Lookup returns the codec called name
+func Lookup(name string) (Codec, bool) {
+	for _, c := range Codecs() {
+		if strings.EqualFold(c.Name(), name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// ForFile returns the codec of filename by its extension
+func ForFile(filename string) (Codec, bool) {
+	ext := strings.ToLower(path.Ext(filename))
+	cc := Codecs()
+	for i := len(cc) - 1; i >= 0; i-- {
+		for _, e := range cc[i].Extensions() {
+			if strings.EqualFold(e, ext) {
+				return cc[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// IsConfigFile returns true when filename has the extension of a registered codec
+func IsConfigFile(filename string) bool {
+	_, ok := ForFile(filename)
+	return ok
+}
+
+// TrimExt returns the base name of filename without the extension of its codec
+func TrimExt(filename string) string {
+	base := path.Base(filename)
+	if _, ok := ForFile(filename); !ok {
+		return base
+	}
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// Detect returns the codec of b by its content
+//
+// JSON, YAML, TOML and HCL are tried in turn, then any other registered
+// codec, and the first which decodes b wins; the line-based formats are
+// told apart by their syntax: [section] headers mean INI, KEY=value lines
+// (optionally exported) mean dotenv and anything else is read as Java
+// properties
+func Detect(b []byte) (Codec, bool) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 {
+		return nil, false
+	}
+	candidates := make([]Codec, 0)
+	for _, name := range []string{"json", "yaml", "toml", "hcl"} {
+		if c, ok := Lookup(name); ok {
+			candidates = append(candidates, c)
+		}
+	}
+	for _, c := range Codecs() {
+		switch strings.ToLower(c.Name()) {
+		case "json", "yaml", "toml", "hcl", "ini", "dotenv", "properties":
+		default:
+			candidates = append(candidates, c)
+		}
+	}
+	for _, c := range candidates {
+		if strings.EqualFold(c.Name(), "json") && trimmed[0] != '{' {
+			continue
+		}
+		if _, err := c.Decode(b); err == nil {
+			return c, true
+		}
+	}
+
+	lineBased := Dotenv
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			lineBased = INI
+		case lineBased == Dotenv && !strings.HasPrefix(line, "export ") && !isEnvAssignment(line):
+			lineBased = Properties
+		}
+		if lineBased == INI {
+			break
+		}
+	}
+	// use the registered codec with that name in case it was replaced
+	c, ok := Lookup(lineBased.Name())
+	if !ok {
+		return nil, false
+	}
+	if _, err := c.Decode(b); err != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// isEnvAssignment returns true when line assigns an upper case environment variable (e.g. DB_HOST=localhost)
+func isEnvAssignment(line string) bool {
+	i := strings.Index(line, "=")
+	if i <= 0 {
+		return false
+	}
+	for _, r := range line[:i] {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Decode decodes b into a map using the codec of filename, detecting the
+// codec by content when filename has no known extension
+func Decode(filename string, b []byte) (map[string]interface{}, error) {
+	c, ok := ForFile(filename)
+	if !ok {
+		if len(bytes.TrimSpace(b)) == 0 {
+			return make(map[string]interface{}), nil
+		}
+		if c, ok = Detect(b); !ok {
+			return nil, fmt.Errorf("unknown config format: %#v is not %s", filename, strings.Join(Names(), ", "))
+		}
+	}
+	m, err := c.Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", c.Name(), err)
+	}
+	return m, nil
+}
+
+// Encode encodes m with the codec called name
+func Encode(name string, m map[string]interface{}) ([]byte, error) {
+	c, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown config format: %#v is not %s", name, strings.Join(Names(), ", "))
+	}
+	return c.Encode(m)
+}
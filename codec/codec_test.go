@@ -0,0 +1,198 @@
+package codec
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		filename string
+		content  string
+		want     map[string]interface{}
+	}{
+		{
+			filename: "default.yaml",
+			content:  "db:\n  host: localhost\n  port: 5432\nfeatures: [a, b]\n",
+			want:     map[string]interface{}{"db": map[string]interface{}{"host": "localhost", "port": 5432}, "features": []interface{}{"a", "b"}},
+		},
+		{
+			filename: "default.yml",
+			content:  "db:\n  host: localhost\n",
+			want:     map[string]interface{}{"db": map[string]interface{}{"host": "localhost"}},
+		},
+		{
+			filename: "dev.json",
+			content:  `{"db": {"host": "dev-db", "port": 5432, "ratio": 0.5}, "features": ["a"]}`,
+			want:     map[string]interface{}{"db": map[string]interface{}{"host": "dev-db", "port": 5432, "ratio": 0.5}, "features": []interface{}{"a"}},
+		},
+		{
+			filename: "prd.toml",
+			content:  "env = \"prd\"\n\n[db]\nhost = \"prd-db\"\nport = 6543\n\n[[servers]]\nname = \"a\"\n",
+			want:     map[string]interface{}{"env": "prd", "db": map[string]interface{}{"host": "prd-db", "port": 6543}, "servers": []interface{}{map[string]interface{}{"name": "a"}}},
+		},
+		{
+			filename: "perf.hcl",
+			content:  "db { host = \"perf-db\" }\nservice \"web\" { port = 80 }\nservice \"api\" { port = 81 }\n",
+			want: map[string]interface{}{
+				"db":      map[string]interface{}{"host": "perf-db"},
+				"service": map[string]interface{}{"web": map[string]interface{}{"port": 80}, "api": map[string]interface{}{"port": 81}},
+			},
+		},
+		{
+			filename: "uat.ini",
+			content:  "name = x\n\n[db]\nhost = uat-db\n\n[db.replica]\nhost = r\n",
+			want:     map[string]interface{}{"name": "x", "db": map[string]interface{}{"host": "uat-db", "replica": map[string]interface{}{"host": "r"}}},
+		},
+		{
+			filename: "stg.env",
+			content:  "# comment\nDB_HOST=stg-db\nexport LOG_LEVEL=\"debug\"\n",
+			want:     map[string]interface{}{"DB_HOST": "stg-db", "LOG_LEVEL": "debug"},
+		},
+		{
+			filename: ".env",
+			content:  "DB_HOST=stg-db\n",
+			want:     map[string]interface{}{"DB_HOST": "stg-db"},
+		},
+		{
+			filename: "qa.properties",
+			content:  "db.host=qa-db\ndb.user = admin\n",
+			want:     map[string]interface{}{"db": map[string]interface{}{"host": "qa-db", "user": "admin"}},
+		},
+		{
+			filename: "no-extension",
+			content:  "DB_HOST=stg-db\n",
+			want:     map[string]interface{}{"DB_HOST": "stg-db"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			got, err := Decode(tt.filename, []byte(tt.content))
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	_, err := Decode("qa.properties", []byte("db=qa-db\ndb.host=qa-db\n"))
+	assert.EqualError(t, err, `decoding properties: "db.host": "db" is already set to a value`)
+
+	_, err = Decode("dev.json", []byte(`{"db": `))
+	assert.EqualError(t, err, "decoding json: unexpected EOF")
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: `{"a": 1}`, want: "json"},
+		{content: "a:\n  b: 1\n", want: "yaml"},
+		{content: "[a]\nb = 1\n", want: "toml"},
+		{content: "a { b = 1 }\n", want: "hcl"},
+		{content: "[a]\nb = c\n", want: "ini"},
+		{content: "A=1\nexport B=two\n", want: "dotenv"},
+		{content: "a.b=c\n", want: "properties"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, ok := Detect([]byte(tt.content))
+			if assert.True(t, ok) {
+				assert.Equal(t, tt.want, got.Name())
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	m := map[string]interface{}{
+		"env":      "dev",
+		"db":       map[string]interface{}{"port": 5432, "host": "dev-db"},
+		"features": []interface{}{"a", "b"},
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "yaml",
+			want: "db:\n    host: dev-db\n    port: 5432\nenv: dev\nfeatures:\n    - a\n    - b\n",
+		},
+		{
+			name: "json",
+			want: "{\n  \"db\": {\n    \"host\": \"dev-db\",\n    \"port\": 5432\n  },\n  \"env\": \"dev\",\n  \"features\": [\n    \"a\",\n    \"b\"\n  ]\n}\n",
+		},
+		{
+			name: "toml",
+			want: "env = \"dev\"\nfeatures = [\"a\", \"b\"]\n\n[db]\nhost = \"dev-db\"\nport = 5432\n",
+		},
+		{
+			name: "dotenv",
+			want: "DB_HOST=\"dev-db\"\nDB_PORT=5432\nENV=\"dev\"\nFEATURES_0=\"a\"\nFEATURES_1=\"b\"\n",
+		},
+		{
+			name: "properties",
+			want: "db.host = dev-db\ndb.port = 5432\nenv = dev\nfeatures[0] = a\nfeatures[1] = b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Encode(tt.name, m)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, string(got))
+			}
+		})
+	}
+}
+
+func TestEncodeCollisions(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		_, err := Encode("dotenv", map[string]interface{}{
+			"db":      map[string]interface{}{"host": "a"},
+			"db_host": "b",
+		})
+		assert.EqualError(t, err, `"db.host" and "db_host" both flatten to "DB_HOST"`)
+	}
+}
+
+func TestEncodeNotSupported(t *testing.T) {
+	_, err := Encode("hcl", map[string]interface{}{"a": 1})
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.False(t, CanEncode(HCL))
+	assert.NotContains(t, EncoderNames(), "ini")
+}
+
+func TestRegister(t *testing.T) {
+	// a format with one key=value pair per line separated by ;
+	semicolons := New("semicolons", []string{".sc"},
+		func(b []byte) (map[string]interface{}, error) {
+			m := make(map[string]interface{})
+			for _, pair := range strings.Split(strings.TrimSpace(string(b)), ";") {
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 {
+					return nil, fmt.Errorf("expected key=value: %#v", pair)
+				}
+				m[kv[0]] = kv[1]
+			}
+			return m, nil
+		}, nil)
+	Register(semicolons)
+
+	c, ok := ForFile("dev.sc")
+	assert.True(t, ok)
+	assert.Equal(t, semicolons, c)
+	assert.Equal(t, "dev", TrimExt("app1/dev.sc"))
+
+	got, err := Decode("dev.sc", []byte("a=1;b=2"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, got)
+	}
+
+	Register(New("semicolons", []string{".semi"}, semicolons.Decode, nil))
+	assert.False(t, IsConfigFile("dev.sc"))
+	assert.True(t, IsConfigFile("dev.semi"))
+}
@@ -0,0 +1,346 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/hashicorp/hcl"
+	"github.com/joho/godotenv"
+	"github.com/magiconair/properties"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func decodeYAML(b []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func encodeYAML(m map[string]interface{}) ([]byte, error) {
+	return yaml.Marshal(m)
+}
+
+func decodeJSON(b []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return normalize(m).(map[string]interface{}), nil
+}
+
+func encodeJSON(m map[string]interface{}) ([]byte, error) {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func decodeTOML(b []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if err := toml.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return normalize(m).(map[string]interface{}), nil
+}
+
+// encodeTOML encodes m as TOML where null values are left out
+func encodeTOML(m map[string]interface{}) ([]byte, error) {
+	b := &bytes.Buffer{}
+	e := toml.NewEncoder(b)
+	e.Indent = ""
+	if err := e.Encode(m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// decodeHCL decodes HCL (v1) where each block is decoded as a list of
+// hashes; blocks are folded back into a single hash so that
+//
+//	service "web" { port = 80 }
+//
+// decodes as {"service": {"web": {"port": 80}}}
+func decodeHCL(b []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if err := hcl.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	v, err := foldHCLBlocks(m)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(v).(map[string]interface{}), nil
+}
+
+func foldHCLBlocks(v interface{}) (interface{}, error) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vv {
+			f, err := foldHCLBlocks(e)
+			if err != nil {
+				return nil, err
+			}
+			vv[k] = f
+		}
+		return vv, nil
+	case []map[string]interface{}:
+		folded := make(map[string]interface{})
+		for _, block := range vv {
+			f, err := foldHCLBlocks(block)
+			if err != nil {
+				return nil, err
+			}
+			// later blocks are merged onto earlier ones
+			if folded, err = v1.Merge(f.(map[string]interface{}), folded); err != nil {
+				return nil, err
+			}
+		}
+		return folded, nil
+	case []interface{}:
+		for i, e := range vv {
+			f, err := foldHCLBlocks(e)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = f
+		}
+		return vv, nil
+	default:
+		return vv, nil
+	}
+}
+
+// decodeDotenv decodes KEY=value lines into a flat hash of strings
+func decodeDotenv(b []byte) (map[string]interface{}, error) {
+	env, err := godotenv.Unmarshal(string(b))
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{}, len(env))
+	for k, v := range env {
+		m[k] = v
+	}
+	return m, nil
+}
+
+// encodeDotenv encodes m as KEY=value lines named by the upper case path
+// to each value (e.g. db.hosts[0] becomes DB_HOSTS_0)
+func encodeDotenv(m map[string]interface{}) ([]byte, error) {
+	flat, err := flatten(m, func(parent string, key string, index bool) string {
+		if parent != "" {
+			key = parent + "_" + key
+		}
+		return envName.ReplaceAllString(strings.ToUpper(key), "_")
+	})
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for k, v := range flat {
+		env[k] = scalarString(v)
+	}
+	s, err := godotenv.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s + "\n"), nil
+}
+
+var envName = regexp.MustCompile("[^A-Z0-9_]")
+
+// scalarString formats a scalar for the line-based formats, where null is empty
+func scalarString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// decodeINI decodes keys outside of any section at the top level and the
+// keys of each section into a hash named by the section; dotted section
+// names nest (e.g. [db.replica])
+func decodeINI(b []byte) (map[string]interface{}, error) {
+	f, err := ini.Load(b)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	for _, s := range f.Sections() {
+		for _, k := range s.Keys() {
+			key := k.Name()
+			if s.Name() != ini.DefaultSection {
+				key = s.Name() + "." + key
+			}
+			if err := nest(m, key, k.Value()); err != nil {
+				return nil, fmt.Errorf("section %#v: %v", s.Name(), err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// decodeProperties decodes Java properties where dotted keys nest (e.g.
+// db.host=localhost decodes as {"db": {"host": "localhost"}})
+func decodeProperties(b []byte) (map[string]interface{}, error) {
+	p, err := properties.Load(b, properties.UTF8)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	for _, k := range p.Keys() {
+		v, _ := p.Get(k)
+		if err := nest(m, k, v); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// encodeProperties encodes m as Java properties named by the dotted path
+// to each value with arrays indexed in brackets (e.g. db.hosts[0])
+func encodeProperties(m map[string]interface{}) ([]byte, error) {
+	flat, err := flatten(m, func(parent string, key string, index bool) string {
+		switch {
+		case index:
+			return parent + "[" + key + "]"
+		case parent == "":
+			return key
+		default:
+			return parent + "." + key
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := properties.NewProperties()
+	p.DisableExpansion = true
+	for _, k := range keys {
+		if _, _, err := p.Set(k, scalarString(flat[k])); err != nil {
+			return nil, err
+		}
+	}
+	b := &bytes.Buffer{}
+	if _, err := p.Write(b, properties.UTF8); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// normalize converts the values decoded by each format into the types
+// decoded from YAML so that formats can be mixed (e.g. int64 and
+// json.Number become int or float64 and []map[string]interface{} becomes
+// []interface{})
+func normalize(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vv {
+			vv[k] = normalize(e)
+		}
+		return vv
+	case []interface{}:
+		for i, e := range vv {
+			vv[i] = normalize(e)
+		}
+		return vv
+	case []map[string]interface{}:
+		l := make([]interface{}, len(vv))
+		for i, e := range vv {
+			l[i] = normalize(e)
+		}
+		return l
+	case int64:
+		if int64(int(vv)) == vv {
+			return int(vv)
+		}
+		return vv
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return normalize(i)
+		}
+		f, _ := vv.Float64()
+		return f
+	default:
+		return vv
+	}
+}
+
+// nest sets value at the dotted key in m, creating a hash for each parent
+// (e.g. "db.host" sets m["db"]["host"])
+func nest(m map[string]interface{}, key string, value interface{}) error {
+	parts := strings.Split(key, ".")
+	for i, p := range parts[:len(parts)-1] {
+		child, ok := m[p]
+		if !ok {
+			child = make(map[string]interface{})
+			m[p] = child
+		}
+		if m, ok = child.(map[string]interface{}); !ok {
+			return fmt.Errorf("%#v: %#v is already set to a value", key, strings.Join(parts[:i+1], "."))
+		}
+	}
+	last := parts[len(parts)-1]
+	if _, ok := m[last].(map[string]interface{}); ok {
+		return fmt.Errorf("%#v: is already a hash", key)
+	}
+	m[last] = value
+	return nil
+}
+
+// flatten returns every scalar below m by a key made of the keys and
+// indexes leading to it, joined by join (e.g. the value at db.hosts[0]);
+// two values whose keys join the same way (e.g. db.host and db_host in a
+// dotenv file) are an error rather than one silently replacing the other
+func flatten(m map[string]interface{}, join func(parent string, key string, index bool) string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	pathByKey := make(map[string]v1.Path)
+	var walk func(prefix string, p v1.Path, v interface{}) error
+	walk = func(prefix string, p v1.Path, v interface{}) error {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			for k, e := range vv {
+				if err := walk(join(prefix, k, false), p.Key(k), e); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for i, e := range vv {
+				if err := walk(join(prefix, strconv.Itoa(i), true), p.Index(i), e); err != nil {
+					return err
+				}
+			}
+		default:
+			if other, ok := pathByKey[prefix]; ok {
+				paths := []string{other.String(), p.String()}
+				sort.Strings(paths)
+				return fmt.Errorf("%#v and %#v both flatten to %#v", paths[0], paths[1], prefix)
+			}
+			pathByKey[prefix] = p
+			result[prefix] = vv
+		}
+		return nil
+	}
+	for k, v := range m {
+		if err := walk(join("", k, false), v1.Path{k}, v); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
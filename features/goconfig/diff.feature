@@ -0,0 +1,61 @@
+Feature: diff
+
+  these commands show the changes between two config files, two
+  folders of config files, or two merged slugs of the same app
+  (e.g. to review a promotion from staging to production)
+
+  Background:
+    Given I have installed "goconfig" locally into the path
+    And I use a fixture named "simple-configuration"
+
+  Scenario: diff two slugs of an app
+    When I successfully run `goconfig diff slugs config app1 stg prd`
+    Then the stdout should contain:
+      """
+      --- app1/stg
+      +++ app1/prd
+      - env: "stg"
+      + env: "prd"
+      """
+
+  Scenario: diff two files as json
+    When I successfully run `goconfig diff files config/app1/dev.yaml config/app1/dev.us-east-1.yaml -o json`
+    Then the stdout should contain:
+      """
+      {
+        "files": [
+          {
+            "from": "config/app1/dev.yaml",
+            "to": "config/app1/dev.us-east-1.yaml",
+            "changes": [
+              {
+                "type": "removed",
+                "path": "env",
+                "from": "dev"
+              },
+              {
+                "type": "removed",
+                "path": "log_level",
+                "from": "DEBUG"
+              },
+              {
+                "type": "added",
+                "path": "region",
+                "to": "us-east-1"
+              }
+            ]
+          }
+        ]
+      }
+      """
+
+  Scenario: diff two folders written by sync folder
+    Given I successfully run `goconfig sync folder config --out-folder before`
+    And a file named "config/app1/prd.yaml" with:
+      """
+      env: prd
+      region: us-west-2
+      """
+    And I successfully run `goconfig sync folder config --out-folder after`
+    When I successfully run `goconfig diff folders before after -o table`
+    Then the stdout should contain "| after/app1/prd.yaml | region | changed | \"unknown\" | \"us-west-2\" |"
@@ -0,0 +1,51 @@
+Feature: explain <source_folder> <app> <slug> <key_path>
+
+  this command can be used to show which file in the convention-based
+  merge hierarchy contributed the merged value of a config key
+
+  Background:
+    Given I have installed "goconfig" locally into the path
+    And I use a fixture named "simple-configuration"
+
+  Scenario: explain a key overridden by a parent slug
+    When I successfully run `goconfig explain config app1 dev.us-east-1 log_level`
+    Then the stdout should contain:
+      """
+      +-----------+--------------------------------+-------+------------+
+      |    KEY    |              FILE              | VALUE |   RESULT   |
+      +-----------+--------------------------------+-------+------------+
+      | log_level | config/app1/default.yaml       | WARN  | overridden |
+      |           | config/app1/dev.yaml           | DEBUG | winner     |
+      |           | config/app1/dev.us-east-1.yaml |       | not set    |
+      +-----------+--------------------------------+-------+------------+
+      """
+
+  Scenario: explain a key as json
+    When I successfully run `goconfig explain config app1 stg env -o json`
+    Then the stdout should contain:
+      """
+      [
+        {
+          "key": "env",
+          "value": "stg",
+          "source": "config/app1/stg.yaml",
+          "chain": [
+            {
+              "file": "config/app1/default.yaml",
+              "value": "unknown",
+              "result": "overridden"
+            },
+            {
+              "file": "config/app1/stg.yaml",
+              "value": "stg",
+              "result": "winner"
+            }
+          ]
+        }
+      ]
+      """
+
+  Scenario: explain an unknown key
+    When I run `goconfig explain config app1 dev missing`
+    Then the exit status should not be 0
+    And the stdout should contain "key \"missing\" not found in app1/dev"
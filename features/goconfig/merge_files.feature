@@ -49,3 +49,60 @@ Feature: merge files <src_path> <dest_path>
         "log_level": "DEBUG"
       }
       """
+
+  Scenario: merge patch deletes keys set to null
+    Given a file named "config/app1/prd.yaml" with:
+      """
+      log_level: ~
+      auth:
+        password: null
+      """
+    When I successfully run `goconfig merge files config/app1/prd.yaml config/app1/default.yaml --merge-patch -o yaml`
+    Then the stdout should contain:
+      """
+      auth:
+        username: default_user
+      env: REQUIRED
+      """
+    And the stdout should not contain "log_level"
+
+  Scenario: merge as a JSON patch
+    When I successfully run `goconfig merge files config/app1/dev.yaml config/app1/default.yaml --as-patch -o json`
+    Then the stdout should contain:
+      """
+      [
+        {
+          "op": "replace",
+          "path": "/auth/password",
+          "value": "dev_pass"
+        },
+        {
+          "op": "replace",
+          "path": "/env",
+          "value": "dev"
+        },
+        {
+          "op": "replace",
+          "path": "/log_level",
+          "value": "DEBUG"
+        }
+      ]
+      """
+
+  Scenario: merge files written in different formats
+    Given a file named "config/app1/dev.toml" with:
+      """
+      env = "dev"
+
+      [auth]
+      password = "toml_pass"
+      """
+    When I successfully run `goconfig merge files config/app1/dev.toml config/app1/default.yaml -o json`
+    Then the stdout should contain:
+      """
+      "password": "toml_pass"
+      """
+    And the stdout should contain:
+      """
+      "env": "dev"
+      """
@@ -0,0 +1,65 @@
+Feature: merge three <base_path> <ours_path> <theirs_path>
+
+  this command applies the changes two config files made to a
+  common base file (e.g. to rebase a team's fork of a config file
+  onto upstream changes) and writes the result to standard out
+
+  Background:
+    Given I have installed "goconfig" locally into the path
+    And a file named "base.yaml" with:
+      """
+      log_level: WARN
+      env: REQUIRED
+      region: unknown
+      """
+    And a file named "ours.yaml" with:
+      """
+      log_level: DEBUG
+      env: REQUIRED
+      region: unknown
+      """
+
+  Scenario: changes to different keys are combined
+    Given a file named "theirs.yaml" with:
+      """
+      log_level: WARN
+      env: REQUIRED
+      region: us-east-1
+      """
+    When I successfully run `goconfig merge three base.yaml ours.yaml theirs.yaml`
+    Then the stdout should contain:
+      """
+      env: REQUIRED
+      log_level: DEBUG
+      region: us-east-1
+      """
+
+  Scenario: conflicting changes fail the merge
+    Given a file named "theirs.yaml" with:
+      """
+      log_level: INFO
+      env: REQUIRED
+      region: unknown
+      """
+    When I run `goconfig merge three base.yaml ours.yaml theirs.yaml`
+    Then the exit status should be 1
+    And the stdout should contain:
+      """
+      found 1 three-way merge conflict(s):
+      - log_level: ours changed it to "DEBUG" but theirs changed it to "INFO"
+      """
+
+  Scenario: conflicting changes resolved with theirs
+    Given a file named "theirs.yaml" with:
+      """
+      log_level: INFO
+      env: REQUIRED
+      region: unknown
+      """
+    When I successfully run `goconfig merge three base.yaml ours.yaml theirs.yaml --resolve theirs`
+    Then the stdout should contain:
+      """
+      env: REQUIRED
+      log_level: INFO
+      region: unknown
+      """
@@ -34,3 +34,197 @@ Feature: sync folder
       log_level: DEBUG
       region: us-east-1
       """
+
+  Scenario: preserve comments and key order
+    Given a file named "config/app1/default.yaml" with:
+      """
+      # app1 defaults
+      log_level: WARN # quiet by default
+      env: unknown
+      region: unknown
+      """
+    When I successfully run `goconfig sync folder config --out-folder out --preserve-formatting`
+    Then the file "out/app1/prd.yaml" should contain:
+      """
+      # app1 defaults
+      log_level: WARN # quiet by default
+      env: prd
+      region: unknown
+      """
+
+  Scenario: overrides can reference anchors from default.yaml and merge keys are deep merged
+    Given a file named "config/app1/default.yaml" with:
+      """
+      db_defaults: &db
+        pool: 5
+        tls:
+          enabled: true
+          verify: true
+      """
+    And a file named "config/app1/dev.yaml" with:
+      """
+      replica:
+        <<: *db
+        tls:
+          verify: false
+      """
+    When I successfully run `goconfig sync folder config --out-folder out`
+    Then the file "out/app1/dev.yaml" should contain:
+      """
+      replica:
+          pool: 5
+          tls:
+              enabled: true
+              verify: false
+      """
+
+  Scenario: app folders can mix config formats
+    Given a file named "config/app2/default.yaml" with:
+      """
+      db:
+        host: localhost
+        port: 5432
+      """
+    And a file named "config/app2/dev.json" with:
+      """
+      { "db": { "host": "dev-db" } }
+      """
+    And a file named "config/app2/prd.properties" with:
+      """
+      db.host=prd-db
+      """
+    When I successfully run `goconfig sync folder config --out-folder out`
+    Then the file "out/app2/dev.yaml" should contain:
+      """
+      db:
+          host: dev-db
+          port: 5432
+      """
+    And the file "out/app2/prd.yaml" should contain:
+      """
+      db:
+          host: prd-db
+          port: 5432
+      """
+
+  Scenario: write another output format
+    When I successfully run `goconfig sync folder config --out-folder out --out-format properties`
+    Then the file "out/app1/prd.properties" should contain:
+      """
+      env = prd
+      log_level = WARN
+      region = unknown
+      """
+
+  Scenario: choose the output format of an app in the manifest
+    Given a file named "config/goconfig.yaml" with:
+      """
+      apps:
+        app1:
+          out_format: configmap
+      """
+    When I successfully run `goconfig sync folder config --out-folder out --out-format json`
+    Then the file "out/app1/prd.yaml" should contain:
+      """
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+          name: app1-prd
+      data:
+          config.yaml: |
+      """
+
+  Scenario: declare the layers of each slug in the manifest
+    Given a file named "config/goconfig.yaml" with:
+      """
+      slugs:
+        prd-eu: [default, prd, eu]
+      """
+    And a file named "config/app1/eu.yaml" with:
+      """
+      region: eu-west-1
+      """
+    When I successfully run `goconfig sync folder config --out-folder out`
+    Then the file "out/app1/prd-eu.yaml" should contain:
+      """
+      env: prd
+      log_level: WARN
+      region: eu-west-1
+      """
+    And the file "out/app1/dev.yaml" should not exist
+
+  Scenario: share layers across apps with a _global folder
+    Given a file named "config/_global/prd.yaml" with:
+      """
+      logging:
+        endpoint: https://logs.example.com
+      """
+    When I successfully run `goconfig sync folder config --out-folder out`
+    Then the file "out/app1/prd.yaml" should contain:
+      """
+      logging:
+          endpoint: https://logs.example.com
+      """
+    And the directory "out/_global" should not exist
+
+  Scenario: an app extends another app
+    Given a file named "config/app1-eu/goconfig.yaml" with:
+      """
+      extends: app1
+      """
+    And a file named "config/app1-eu/default.yaml" with:
+      """
+      region: eu-west-1
+      """
+    When I successfully run `goconfig sync folder config --out-folder out`
+    Then the file "out/app1-eu/prd.yaml" should contain:
+      """
+      env: prd
+      log_level: WARN
+      region: eu-west-1
+      """
+    And the file "out/app1-eu/dev.us-east-1.yaml" should contain:
+      """
+      env: dev
+      log_level: DEBUG
+      region: eu-west-1
+      """
+
+  Scenario: apps nested in group folders are written under their relative path
+    Given a file named "config/team-a/payments/api/default.yaml" with:
+      """
+      name: api
+      """
+    And a file named "config/team-a/payments/api/prd.yaml" with:
+      """
+      replicas: 3
+      """
+    When I successfully run `goconfig sync folder config --out-folder out`
+    Then the file "out/team-a/payments/api/prd.yaml" should contain:
+      """
+      name: api
+      replicas: 3
+      """
+    And the file "out/app1/prd.yaml" should exist
+
+  Scenario: include a shared file into a config file
+    Given a file named "config/_shared/logging.yaml" with:
+      """
+      endpoint: http://logs.local
+      level: INFO
+      """
+    And a file named "config/app1/prd.yaml" with:
+      """
+      env: prd
+      logging:
+        $include: ../_shared/logging.yaml
+        level: ERROR
+      """
+    When I successfully run `goconfig sync folder config --out-folder out`
+    Then the file "out/app1/prd.yaml" should contain:
+      """
+      logging:
+          endpoint: http://logs.local
+          level: ERROR
+      """
+    And the directory "out/_shared" should not exist
@@ -3,14 +3,19 @@ module github.com/davidalpert/go-deep-merge
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/aws/aws-sdk-go v1.44.191
 	github.com/davidalpert/go-printers v0.3.0
+	github.com/hashicorp/hcl v1.0.0
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/joho/godotenv v1.5.1
+	github.com/magiconair/properties v1.8.7
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/spf13/afero v1.9.3
 	github.com/spf13/cobra v1.6.1
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/text v0.5.0 // indirect
+	gopkg.in/ini.v1 v1.67.0
 	gopkg.in/yaml.v3 v3.0.1
 )
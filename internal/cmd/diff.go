@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/davidalpert/go-printers/v1"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+func NewCmdDiff(ioStreams printers.IOStreams) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "diff",
+		Aliases: []string{"d"},
+		Short:   "diff subcommands",
+		Args:    cobra.NoArgs,
+	}
+
+	cmd.AddCommand(NewCmdDiffFiles(ioStreams))
+	cmd.AddCommand(NewCmdDiffFolders(ioStreams))
+	cmd.AddCommand(NewCmdDiffSlugs(ioStreams))
+
+	return cmd
+}
+
+// DiffPrinterOptions are shared by the diff subcommands
+type DiffPrinterOptions struct {
+	*printers.PrinterOptions
+	Color        string
+	IdentityKeys []string
+}
+
+func NewDiffPrinterOptions(ioStreams printers.IOStreams) *DiffPrinterOptions {
+	return &DiffPrinterOptions{
+		PrinterOptions: printers.NewPrinterOptions().WithStreams(ioStreams).WithDefaultOutput("text").WithTableWriter("", func(t *tablewriter.Table) {}),
+		Color:          "auto",
+	}
+}
+
+func (o *DiffPrinterOptions) addDiffFlags(cmd *cobra.Command) {
+	o.PrinterOptions.AddPrinterFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.Color, "color", o.Color, "color text output: one of auto|always|never")
+	cmd.Flags().StringArrayVar(&o.IdentityKeys, "identity-key", nil, "compare hashes within arrays by a field name rather than by index, either everywhere (e.g. name) or at a path (e.g. spec.containers=name)")
+}
+
+// Validate the options
+func (o *DiffPrinterOptions) Validate() error {
+	switch o.Color {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("invalid color: %s\nvalid color values are: auto|always|never", o.Color)
+	}
+	return o.PrinterOptions.Validate()
+}
+
+// diffConfig returns the v1.Config which identifies hashes within arrays
+func (o *DiffPrinterOptions) diffConfig() *v1.Config {
+	cfg := v1.NewConfig()
+	for _, k := range o.IdentityKeys {
+		if i := strings.LastIndex(k, "="); i >= 0 {
+			cfg = cfg.WithMergeHashArraysByKeyAtPath(k[:i], k[i+1:])
+		} else {
+			cfg = cfg.WithMergeHashArraysByKey(k)
+		}
+	}
+	return cfg
+}
+
+// useColor returns true when text output should be colored
+func (o *DiffPrinterOptions) useColor() bool {
+	switch o.Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := o.Out.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// DiffFileResult lists the changes which turn one config file into another;
+// a file missing on one side is named /dev/null and compared as empty
+type DiffFileResult struct {
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Changes []v1.Change `json:"changes"`
+}
+
+// DiffResults renders as a unified-style diff in text output
+type DiffResults struct {
+	Files []DiffFileResult `json:"files"`
+	color bool
+}
+
+const (
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiBold  = "\033[1m"
+	ansiReset = "\033[0m"
+)
+
+func (r DiffResults) String() string {
+	sb := &strings.Builder{}
+	for _, f := range r.Files {
+		if len(f.Changes) == 0 {
+			continue
+		}
+		r.writeLine(sb, ansiBold, "--- %s", f.From)
+		r.writeLine(sb, ansiBold, "+++ %s", f.To)
+		for _, c := range f.Changes {
+			switch c.Type {
+			case v1.ChangeAdded:
+				r.writeLine(sb, ansiGreen, "+ %s: %s", c.Path, compactValue(c.To))
+			case v1.ChangeRemoved:
+				r.writeLine(sb, ansiRed, "- %s: %s", c.Path, compactValue(c.From))
+			default:
+				r.writeLine(sb, ansiRed, "- %s: %s", c.Path, compactValue(c.From))
+				r.writeLine(sb, ansiGreen, "+ %s: %s", c.Path, compactValue(c.To))
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (r DiffResults) writeLine(w io.Writer, color string, format string, a ...interface{}) {
+	line := fmt.Sprintf(format, a...)
+	if r.color {
+		line = color + line + ansiReset
+	}
+	fmt.Fprintln(w, line)
+}
+
+// compactValue formats v as single-line JSON
+func compactValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// writeDiff writes results as a unified-style diff, a table, or machine-readable output
+func (o *DiffPrinterOptions) writeDiff(results []DiffFileResult) error {
+	r := DiffResults{Files: results, color: o.useColor()}
+	if o.FormatCategory() == "text" {
+		// write directly as values may contain formatting verbs
+		_, err := fmt.Fprint(o.Out, r.String())
+		return err
+	}
+	return o.WithTableWriter("", func(t *tablewriter.Table) {
+		t.SetHeader([]string{"File", "Key", "Change", "From", "To"})
+		t.SetAutoMergeCellsByColumnIndex([]int{0})
+		t.SetAutoWrapText(false)
+		for _, f := range results {
+			file := f.To
+			if file == "/dev/null" {
+				file = f.From
+			}
+			for _, c := range f.Changes {
+				from, to := "", ""
+				if c.Type != v1.ChangeAdded {
+					from = compactValue(c.From)
+				}
+				if c.Type != v1.ChangeRemoved {
+					to = compactValue(c.To)
+				}
+				t.Append([]string{file, c.Path.String(), string(c.Type), from, to})
+			}
+		}
+	}).WriteOutput(r)
+}
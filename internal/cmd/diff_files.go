@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/codec"
+	"github.com/davidalpert/go-deep-merge/internal/app"
+	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/davidalpert/go-printers/v1"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+type DiffFilesOptions struct {
+	*DiffPrinterOptions
+	FromFile string
+	ToFile   string
+}
+
+func NewDiffFilesOptions(ioStreams printers.IOStreams) *DiffFilesOptions {
+	return &DiffFilesOptions{
+		DiffPrinterOptions: NewDiffPrinterOptions(ioStreams),
+	}
+}
+
+func NewCmdDiffFiles(ioStreams printers.IOStreams) *cobra.Command {
+	o := NewDiffFilesOptions(ioStreams)
+	var cmd = &cobra.Command{
+		Use:     "files <from_file> <to_file>",
+		Short:   "show the changes between two config files",
+		Aliases: []string{"f", "fs", "file"},
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	o.addDiffFlags(cmd)
+
+	return cmd
+}
+
+// Complete the options
+func (o *DiffFilesOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.FromFile = args[0]
+	o.ToFile = args[1]
+	return nil
+}
+
+// Validate the options
+func (o *DiffFilesOptions) Validate() error {
+	return o.DiffPrinterOptions.Validate()
+}
+
+// Run the command
+func (o *DiffFilesOptions) Run() error {
+	from, err := readConfigFile(o.FromFile)
+	if err != nil {
+		return err
+	}
+	to, err := readConfigFile(o.ToFile)
+	if err != nil {
+		return err
+	}
+
+	return o.writeDiff([]DiffFileResult{
+		{From: o.FromFile, To: o.ToFile, Changes: v1.DiffWithOptions(from, to, o.diffConfig())},
+	})
+}
+
+// readConfigFile reads a config file in any supported format into a map
+func readConfigFile(filename string) (map[string]interface{}, error) {
+	b, err := afero.ReadFile(app.Fs, filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %#v: %#v", filename, err)
+	}
+	m, err := codec.Decode(filename, b)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling %#v: %#v", filename, err)
+	}
+	return m, nil
+}
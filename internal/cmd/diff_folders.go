@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/codec"
+	"github.com/davidalpert/go-deep-merge/internal/app"
+	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/davidalpert/go-printers/v1"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+type DiffFoldersOptions struct {
+	*DiffPrinterOptions
+	FromFolder string
+	ToFolder   string
+}
+
+func NewDiffFoldersOptions(ioStreams printers.IOStreams) *DiffFoldersOptions {
+	return &DiffFoldersOptions{
+		DiffPrinterOptions: NewDiffPrinterOptions(ioStreams),
+	}
+}
+
+func NewCmdDiffFolders(ioStreams printers.IOStreams) *cobra.Command {
+	o := NewDiffFoldersOptions(ioStreams)
+	var cmd = &cobra.Command{
+		Use:     "folders <from_folder> <to_folder>",
+		Short:   "show the changes between two folders of config files (e.g. written by sync folder)",
+		Aliases: []string{"folder", "dirs"},
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	o.addDiffFlags(cmd)
+
+	return cmd
+}
+
+// Complete the options
+func (o *DiffFoldersOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.FromFolder = args[0]
+	o.ToFolder = args[1]
+	return nil
+}
+
+// Validate the options
+func (o *DiffFoldersOptions) Validate() error {
+	return o.DiffPrinterOptions.Validate()
+}
+
+// Run the command
+func (o *DiffFoldersOptions) Run() error {
+	fromFiles, err := listConfigFiles(o.FromFolder)
+	if err != nil {
+		return err
+	}
+	toFiles, err := listConfigFiles(o.ToFolder)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0)
+	for name := range fromFiles {
+		names = append(names, name)
+	}
+	for name := range toFiles {
+		if !fromFiles[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	results := make([]DiffFileResult, 0)
+	for _, name := range names {
+		r := DiffFileResult{From: "/dev/null", To: "/dev/null"}
+		from, to := make(map[string]interface{}), make(map[string]interface{})
+		if fromFiles[name] {
+			r.From = path.Join(o.FromFolder, name)
+			if from, err = readConfigFile(r.From); err != nil {
+				return err
+			}
+		}
+		if toFiles[name] {
+			r.To = path.Join(o.ToFolder, name)
+			if to, err = readConfigFile(r.To); err != nil {
+				return err
+			}
+		}
+		r.Changes = v1.DiffWithOptions(from, to, o.diffConfig())
+		results = append(results, r)
+	}
+
+	return o.writeDiff(results)
+}
+
+// listConfigFiles returns the set of config files in any supported format below folder by their path relative to folder
+func listConfigFiles(folder string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := afero.Walk(app.Fs, folder, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && codec.IsConfigFile(p) {
+			rel, err := filepath.Rel(folder, p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading folder %#v: %#v", folder, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/cfgset"
+	"github.com/davidalpert/go-deep-merge/internal/app"
+	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/davidalpert/go-printers/v1"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+)
+
+type DiffSlugsOptions struct {
+	*DiffPrinterOptions
+	cfgset.MergeOptions
+	AppDir   string
+	FromSlug string
+	ToSlug   string
+}
+
+func NewDiffSlugsOptions(ioStreams printers.IOStreams) *DiffSlugsOptions {
+	return &DiffSlugsOptions{
+		DiffPrinterOptions: NewDiffPrinterOptions(ioStreams),
+	}
+}
+
+func NewCmdDiffSlugs(ioStreams printers.IOStreams) *cobra.Command {
+	o := NewDiffSlugsOptions(ioStreams)
+	var cmd = &cobra.Command{
+		Use:     "slugs <source_folder> <app> <from_slug> <to_slug>",
+		Short:   "show the changes between two merged slugs of an app (e.g. stg and prd)",
+		Aliases: []string{"s", "slug"},
+		Args:    cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	o.addDiffFlags(cmd)
+	cmd.Flags().BoolVarP(&o.Debug, "debug", "d", false, "enable debug output")
+
+	return cmd
+}
+
+// Complete the options
+func (o *DiffSlugsOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.SourceFolder = args[0]
+	o.AppDir = args[1]
+	o.FromSlug = args[2]
+	o.ToSlug = args[3]
+	return nil
+}
+
+// Validate the options
+func (o *DiffSlugsOptions) Validate() error {
+	return o.DiffPrinterOptions.Validate()
+}
+
+// Run the command
+func (o *DiffSlugsOptions) Run() error {
+	mergeResults, err := cfgset.Merge(app.Fs, o.MergeOptions)
+	if err != nil {
+		return err
+	}
+
+	var appResult *cfgset.MergeResult
+	appDirs := make([]string, 0)
+	for i, r := range mergeResults {
+		appDirs = append(appDirs, r.AppDir)
+		if r.AppDir == o.AppDir {
+			appResult = &mergeResults[i]
+		}
+	}
+	if appResult == nil {
+		sort.Strings(appDirs)
+		return fmt.Errorf("app %#v not found in %#v: found apps are: %s", o.AppDir, o.SourceFolder, strings.Join(appDirs, ", "))
+	}
+
+	from, err := o.slugResult(appResult, o.FromSlug)
+	if err != nil {
+		return err
+	}
+	to, err := o.slugResult(appResult, o.ToSlug)
+	if err != nil {
+		return err
+	}
+
+	return o.writeDiff([]DiffFileResult{
+		{From: fmt.Sprintf("%s/%s", o.AppDir, o.FromSlug), To: fmt.Sprintf("%s/%s", o.AppDir, o.ToSlug), Changes: v1.DiffWithOptions(from, to, o.diffConfig())},
+	})
+}
+
+func (o *DiffSlugsOptions) slugResult(appResult *cfgset.MergeResult, slug string) (map[string]interface{}, error) {
+	if r, ok := appResult.MergeBySlug[slug]; ok {
+		return r, nil
+	}
+	slugs := make([]string, 0)
+	for s := range appResult.MergeBySlug {
+		slugs = append(slugs, s)
+	}
+	sort.Strings(slugs)
+	return nil, fmt.Errorf("slug %#v not found for app %#v: found slugs are: %s", slug, o.AppDir, strings.Join(slugs, ", "))
+}
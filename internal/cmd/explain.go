@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/cfgset"
+	"github.com/davidalpert/go-deep-merge/internal/app"
+	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/davidalpert/go-printers/v1"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+)
+
+type ExplainOptions struct {
+	*printers.PrinterOptions
+	cfgset.MergeOptions
+	AppDir  string
+	Slug    string
+	KeyPath string
+}
+
+func NewExplainOptions(ioStreams printers.IOStreams) *ExplainOptions {
+	return &ExplainOptions{
+		PrinterOptions: printers.NewPrinterOptions().WithStreams(ioStreams).WithDefaultTableWriter(),
+	}
+}
+
+func NewCmdExplain(ioStreams printers.IOStreams) *cobra.Command {
+	o := NewExplainOptions(ioStreams)
+	var cmd = &cobra.Command{
+		Use:     "explain <source_folder> <app> <slug> <key_path>",
+		Short:   "show which files contributed to a merged config value",
+		Aliases: []string{"x", "blame"},
+		Args:    cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	o.PrinterOptions.AddPrinterFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&o.Debug, "debug", "d", false, "enable debug output")
+
+	return cmd
+}
+
+// Complete the options
+func (o *ExplainOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.SourceFolder = args[0]
+	o.AppDir = args[1]
+	o.Slug = args[2]
+	o.KeyPath = v1.ParsePath(strings.TrimPrefix(args[3], ".")).String()
+	return nil
+}
+
+// Validate the options
+func (o *ExplainOptions) Validate() error {
+	if o.KeyPath == "" {
+		return fmt.Errorf("key path cannot be empty")
+	}
+	return o.PrinterOptions.Validate()
+}
+
+// ExplainKeyResult describes how the merged value of a single key came to be
+type ExplainKeyResult struct {
+	Key    string        `json:"key"`
+	Value  interface{}   `json:"value"`
+	Source string        `json:"source"`
+	Chain  []ExplainStep `json:"chain"`
+}
+
+// ExplainStep describes what one file in the merge hierarchy contributed to a key
+type ExplainStep struct {
+	File   string      `json:"file"`
+	Value  interface{} `json:"value,omitempty"`
+	Result string      `json:"result"`
+}
+
+// Run the command
+func (o *ExplainOptions) Run() error {
+	mergeResults, err := cfgset.Merge(app.Fs, o.MergeOptions)
+	if err != nil {
+		return err
+	}
+
+	var appResult *cfgset.MergeResult
+	appDirs := make([]string, 0)
+	for i, r := range mergeResults {
+		appDirs = append(appDirs, r.AppDir)
+		if r.AppDir == o.AppDir {
+			appResult = &mergeResults[i]
+		}
+	}
+	if appResult == nil {
+		sort.Strings(appDirs)
+		return fmt.Errorf("app %#v not found in %#v: found apps are: %s", o.AppDir, o.SourceFolder, strings.Join(appDirs, ", "))
+	}
+
+	provenance, ok := appResult.ProvenanceBySlug[o.Slug]
+	if !ok {
+		slugs := make([]string, 0)
+		for s := range appResult.ProvenanceBySlug {
+			slugs = append(slugs, s)
+		}
+		sort.Strings(slugs)
+		return fmt.Errorf("slug %#v not found for app %#v: found slugs are: %s", o.Slug, o.AppDir, strings.Join(slugs, ", "))
+	}
+
+	// explain the key itself or, when it names a hash, every key below it
+	keys := make([]string, 0)
+	for k := range provenance {
+		if k == o.KeyPath || strings.HasPrefix(k, o.KeyPath+".") || strings.HasPrefix(k, o.KeyPath+"[") {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("key %#v not found in %s/%s", o.KeyPath, o.AppDir, o.Slug)
+	}
+	sort.Strings(keys)
+
+	results := make([]ExplainKeyResult, 0)
+	for _, k := range keys {
+		results = append(results, explainKey(k, provenance[k], appResult.FilesBySlug[o.Slug]))
+	}
+
+	return o.WithTableWriter(fmt.Sprintf("%s/%s", o.AppDir, o.Slug), func(t *tablewriter.Table) {
+		t.SetHeader([]string{"Key", "File", "Value", "Result"})
+		t.SetAutoMergeCellsByColumnIndex([]int{0})
+		for _, r := range results {
+			for _, step := range r.Chain {
+				value := ""
+				if step.Value != nil {
+					value = fmt.Sprintf("%v", step.Value)
+				}
+				t.Append([]string{r.Key, step.File, value, step.Result})
+			}
+		}
+	}).WriteOutput(results)
+}
+
+// explainKey lines up the contributions recorded for a key with the files merged to produce it
+func explainKey(key string, origin *v1.Origin, files []string) ExplainKeyResult {
+	contributionByFile := make(map[string]v1.Contribution)
+	for _, c := range origin.Chain() {
+		// a value included into a file is listed against that file
+		f := strings.SplitN(c.Source, cfgset.IncludeSeparator, 2)[0]
+		contributionByFile[f] = c
+	}
+
+	result := ExplainKeyResult{
+		Key:    key,
+		Value:  origin.Value,
+		Source: origin.Source,
+		Chain:  make([]ExplainStep, 0),
+	}
+	for _, f := range files {
+		step := ExplainStep{File: f, Result: "not set"}
+		if c, ok := contributionByFile[f]; ok {
+			step.File = c.Source
+			step.Value = c.Value
+			step.Result = "overridden"
+			if c.Deleted {
+				step.Result = "removed"
+			} else if c.Source == origin.Source {
+				step.Result = "winner"
+			}
+		}
+		result.Chain = append(result.Chain, step)
+	}
+	return result
+}
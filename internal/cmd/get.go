@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/davidalpert/go-deep-merge/internal/cfgset"
+	"github.com/davidalpert/go-deep-merge/cfgset"
 	"github.com/davidalpert/go-deep-merge/internal/provider"
 	"github.com/davidalpert/go-printers/v1"
 	"github.com/spf13/cobra"
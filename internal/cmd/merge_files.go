@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/davidalpert/go-deep-merge/codec"
 	"github.com/davidalpert/go-deep-merge/internal/app"
 	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/davidalpert/go-deep-merge/v1/patch"
 	"github.com/davidalpert/go-printers/v1"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
@@ -12,9 +14,16 @@ import (
 
 type MergeFilesOptions struct {
 	*printers.PrinterOptions
-	Source      []byte
-	Destination []byte
-	Debug       bool
+	SourceFile      string
+	Source          []byte
+	DestinationFile string
+	Destination     []byte
+	Debug           bool
+	Strict          bool
+	MergePatch      bool
+	AsPatch         bool
+	// PreserveFormatting writes the merge as a YAML document which keeps the comments, key order and style of the files
+	PreserveFormatting bool
 }
 
 func NewMergeFilesOptions(ioStreams printers.IOStreams) *MergeFilesOptions {
@@ -43,12 +52,18 @@ func NewCmdMergeFiles(ioStreams printers.IOStreams) *cobra.Command {
 
 	o.PrinterOptions.AddPrinterFlags(cmd.Flags())
 	cmd.Flags().BoolVarP(&o.Debug, "debug", "d", false, "enable debug output")
+	cmd.Flags().BoolVar(&o.Strict, "strict", false, "fail and report every key where a hash or array would be merged with a different type")
+	cmd.Flags().BoolVar(&o.AsPatch, "as-patch", false, "write the JSON Patch (RFC 6902) which turns dest_file into the merged result")
+	cmd.Flags().BoolVar(&o.PreserveFormatting, "preserve-formatting", false, "write yaml which keeps the comments, key order and style of the merged files rather than sorting keys")
+	cmd.Flags().BoolVar(&o.MergePatch, "merge-patch", false, "merge with JSON Merge Patch (RFC 7386) semantics: null values delete keys and arrays are replaced")
 
 	return cmd
 }
 
 // Complete the options
 func (o *MergeFilesOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.SourceFile = args[0]
+	o.DestinationFile = args[1]
 	if b, err := afero.ReadFile(app.Fs, args[0]); err != nil {
 		return fmt.Errorf("reading %#v: %#v", args[0], err)
 	} else {
@@ -64,17 +79,21 @@ func (o *MergeFilesOptions) Complete(cmd *cobra.Command, args []string) error {
 
 // Validate the options
 func (o *MergeFilesOptions) Validate() error {
+	if o.PreserveFormatting && o.AsPatch {
+		return fmt.Errorf("--preserve-formatting cannot be combined with --as-patch")
+	}
 	return o.PrinterOptions.Validate()
 }
 
 // Run the command
 func (o *MergeFilesOptions) Run() error {
-	var src, dest map[string]interface{}
-	if err := yaml.Unmarshal(o.Source, &src); err != nil {
+	src, err := codec.Decode(o.SourceFile, o.Source)
+	if err != nil {
 		return fmt.Errorf("unmarshalling src: %#v", err)
 	}
 
-	if err := yaml.Unmarshal(o.Destination, &dest); err != nil {
+	dest, err := codec.Decode(o.DestinationFile, o.Destination)
+	if err != nil {
 		return fmt.Errorf("unmarshalling dest: %#v", err)
 	}
 
@@ -84,10 +103,67 @@ func (o *MergeFilesOptions) Run() error {
 	//o.WriteOutput(dest)
 	//fmt.Fprintln(o.Out, "result:")
 
-	r, err := v1.MergeWithOptions(src, dest, v1.NewConfigDeeperMergeBang().WithMergeHashArrays(true).WithDebug(o.Debug))
-	if err != nil {
+	cfg := v1.NewConfigDeeperMergeBang().WithMergeHashArrays(true)
+	if o.MergePatch {
+		cfg = v1.NewConfigMergePatch()
+	}
+
+	if o.PreserveFormatting {
+		return o.writeFormattedMerge(cfg.WithCollectConflicts(o.Strict).WithDebug(o.Debug))
+	}
+
+	r, err := v1.MergeCopyWithOptions(src, dest, cfg.WithCollectConflicts(o.Strict).WithDebug(o.Debug))
+	if conflicts, ok := err.(v1.MergeConflictsError); ok {
+		return conflicts
+	} else if err != nil {
 		return fmt.Errorf("merging files: %#v", err)
 	}
 
+	if o.AsPatch {
+		return o.WriteOutput(patch.Generate(dest, r))
+	}
 	return o.WriteOutput(r)
 }
+
+// writeFormattedMerge merges the files as yaml.Nodes and writes the result as YAML
+func (o *MergeFilesOptions) writeFormattedMerge(cfg *v1.Config) error {
+	src, err := decodeFileNode(o.SourceFile, o.Source)
+	if err != nil {
+		return fmt.Errorf("unmarshalling src: %#v", err)
+	}
+	dest, err := decodeFileNode(o.DestinationFile, o.Destination)
+	if err != nil {
+		return fmt.Errorf("unmarshalling dest: %#v", err)
+	}
+
+	r, err := v1.MergeNodesWithOptions(src, dest, cfg)
+	if conflicts, ok := err.(v1.MergeConflictsError); ok {
+		return conflicts
+	} else if err != nil {
+		return fmt.Errorf("merging files: %#v", err)
+	}
+
+	b, err := encodeNode(r)
+	if err != nil {
+		return fmt.Errorf("marshalling result: %#v", err)
+	}
+	_, err = o.Out.Write(b)
+	return err
+}
+
+// decodeFileNode parses a YAML file as a yaml.Node, converting files in
+// other formats so that they can be merged with YAML files
+func decodeFileNode(filename string, b []byte) (*yaml.Node, error) {
+	var n yaml.Node
+	if c, ok := codec.ForFile(filename); ok && c != codec.YAML {
+		m, err := codec.Decode(filename, b)
+		if err != nil {
+			return nil, err
+		}
+		return &n, n.Encode(m)
+	}
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	v1 "github.com/davidalpert/go-deep-merge/v1"
+	"github.com/davidalpert/go-printers/v1"
+	"github.com/spf13/cobra"
+)
+
+type MergeThreeOptions struct {
+	*printers.PrinterOptions
+	BaseFile   string
+	OursFile   string
+	TheirsFile string
+	Resolve    string
+	Resolution v1.Resolution
+	Debug      bool
+}
+
+func NewMergeThreeOptions(ioStreams printers.IOStreams) *MergeThreeOptions {
+	return &MergeThreeOptions{
+		PrinterOptions: printers.NewPrinterOptions().WithStreams(ioStreams).WithDefaultOutput("yaml"),
+		Resolve:        "fail",
+	}
+}
+
+func NewCmdMergeThree(ioStreams printers.IOStreams) *cobra.Command {
+	o := NewMergeThreeOptions(ioStreams)
+	var cmd = &cobra.Command{
+		Use:     "three <base_file> <ours_file> <theirs_file>",
+		Short:   "apply the changes two config files made to a common base file",
+		Aliases: []string{"3", "three-way"},
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	o.PrinterOptions.AddPrinterFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&o.Debug, "debug", "d", false, "enable debug output")
+	cmd.Flags().StringVar(&o.Resolve, "resolve", o.Resolve, "how to resolve keys both files changed differently: one of fail|ours|theirs|markers")
+
+	return cmd
+}
+
+// Complete the options
+func (o *MergeThreeOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.BaseFile = args[0]
+	o.OursFile = args[1]
+	o.TheirsFile = args[2]
+	return nil
+}
+
+// Validate the options
+func (o *MergeThreeOptions) Validate() error {
+	r, err := v1.ParseResolution(o.Resolve)
+	if err != nil {
+		return err
+	}
+	o.Resolution = r
+	return o.PrinterOptions.Validate()
+}
+
+// Run the command
+func (o *MergeThreeOptions) Run() error {
+	base, err := readConfigFile(o.BaseFile)
+	if err != nil {
+		return err
+	}
+	ours, err := readConfigFile(o.OursFile)
+	if err != nil {
+		return err
+	}
+	theirs, err := readConfigFile(o.TheirsFile)
+	if err != nil {
+		return err
+	}
+
+	r, conflicts, err := v1.Merge3WithOptions(base, ours, theirs, v1.NewConfig().WithConflictResolution(o.Resolution).WithDebug(o.Debug))
+	if err != nil {
+		return err
+	}
+	if err := o.writeResolvedConflicts(conflicts); err != nil {
+		return err
+	}
+
+	return o.WriteOutput(r)
+}
+
+// writeResolvedConflicts reports each conflict resolved by o.Resolution on the error stream so the merged output stays parseable
+func (o *MergeThreeOptions) writeResolvedConflicts(conflicts v1.Merge3ConflictsError) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(o.ErrOut, "resolved %d three-way merge conflict(s) with %s:\n", len(conflicts), o.Resolution); err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		if _, err := fmt.Fprintf(o.ErrOut, "- %s\n", c.Error()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
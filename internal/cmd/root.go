@@ -64,6 +64,8 @@ func NewRootCmd(ioStreams printers.IOStreams) *cobra.Command {
 	}
 
 	// Register subcommands
+	rootCmd.AddCommand(NewCmdDiff(ioStreams))
+	rootCmd.AddCommand(NewCmdExplain(ioStreams))
 	rootCmd.AddCommand(NewCmdGet(ioStreams))
 	rootCmd.AddCommand(NewCmdMerge(ioStreams))
 	rootCmd.AddCommand(NewCmdSync(ioStreams))
@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"github.com/davidalpert/go-deep-merge/cfgset"
+	"github.com/davidalpert/go-deep-merge/codec"
 	"github.com/davidalpert/go-deep-merge/internal/app"
-	"github.com/davidalpert/go-deep-merge/internal/cfgset"
 	"github.com/davidalpert/go-printers/v1"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 	"os"
 	"path"
+	"regexp"
+	"strings"
 )
 
 type SyncFolderOptions struct {
@@ -48,7 +52,8 @@ func NewCmdSyncFolder(ioStreams printers.IOStreams) *cobra.Command {
 
 	cmd.Flags().BoolVarP(&o.Debug, "debug", "d", false, "enable debug output")
 	cmd.Flags().StringVar(&o.OutFolder, "out-folder", "out", "folder to place output")
-	//cmd.Flags().StringVar(&o.OutFormat, "out-format", "out", "format for output")
+	cmd.Flags().BoolVar(&o.PreserveFormatting, "preserve-formatting", false, "keep the comments, key order and style of the merged files rather than sorting keys")
+	cmd.Flags().StringVar(&o.OutFormat, "out-format", o.OutFormat, fmt.Sprintf("format for output: one of %s (an app can override it with out_format in %s)", strings.Join(outFormats(), "|"), cfgset.ManifestFile))
 
 	return cmd
 }
@@ -61,12 +66,15 @@ func (o *SyncFolderOptions) Complete(cmd *cobra.Command, args []string) error {
 
 // Validate the options
 func (o *SyncFolderOptions) Validate() error {
+	if err := validateOutFormat(o.OutFormat); err != nil {
+		return err
+	}
 	return o.PrinterOptions.Validate()
 }
 
 // Run the command
 func (o *SyncFolderOptions) Run() error {
-	result, err := cfgset.Merge(o.MergeOptions)
+	result, err := cfgset.Merge(app.Fs, o.MergeOptions)
 	if err != nil {
 		return err
 	}
@@ -75,15 +83,28 @@ func (o *SyncFolderOptions) Run() error {
 		return fmt.Errorf("making %#v: %#v", o.OutFolder, err)
 	}
 
+	manifest, err := cfgset.ReadManifest(app.Fs, o.SourceFolder)
+	if err != nil {
+		return err
+	}
+
 	for _, appResult := range result {
 		appOutDir := path.Join(o.OutFolder, appResult.AppDir)
 		if err = app.Fs.MkdirAll(appOutDir, os.ModePerm); err != nil {
 			return fmt.Errorf("making %#v: %#v", appOutDir, err)
 		}
 
+		format := o.OutFormat
+		if f := manifest.Apps[appResult.AppDir].OutFormat; f != "" {
+			if err = validateOutFormat(f); err != nil {
+				return fmt.Errorf("%s: app %#v: %v", cfgset.ManifestFile, appResult.AppDir, err)
+			}
+			format = f
+		}
+
 		for slug, mergeResult := range appResult.MergeBySlug {
-			outFile := path.Join(appOutDir, fmt.Sprintf("%s.%s", slug, o.OutFormat))
-			b, err := yaml.Marshal(mergeResult)
+			ext, b, err := encodeOutput(format, appResult, slug)
+			outFile := path.Join(appOutDir, slug+ext)
 			if err != nil {
 				return fmt.Errorf("marshalling %#v to %#v: %#v", mergeResult, outFile, err)
 			}
@@ -99,3 +120,81 @@ func (o *SyncFolderOptions) Run() error {
 	//return o.WithDefaultOutput("json").WriteOutput(result)
 	return nil
 }
+
+// encodeNode writes a YAML document with the two space indent used by most hand-written config files
+func encodeNode(node *yaml.Node) ([]byte, error) {
+	b := &bytes.Buffer{}
+	e := yaml.NewEncoder(b)
+	e.SetIndent(2)
+	if err := e.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// configMapFormat writes each slug as a Kubernetes ConfigMap holding the merge as config.yaml
+const configMapFormat = "configmap"
+
+func outFormats() []string {
+	return append(codec.EncoderNames(), configMapFormat)
+}
+
+func validateOutFormat(format string) error {
+	if !app.StringInSlice(outFormats(), format) {
+		return fmt.Errorf("invalid out format: %s\nvalid out format values are: %s", format, strings.Join(outFormats(), "|"))
+	}
+	return nil
+}
+
+// encodeOutput returns the file extension and content of the merge for
+// slug in format; YAML keeps the formatting of the merged files when
+// NodeBySlug has the slug and every other format sorts keys
+func encodeOutput(format string, r cfgset.MergeResult, slug string) (string, []byte, error) {
+	m := r.MergeBySlug[slug]
+	switch format {
+	case "yaml":
+		if node, ok := r.NodeBySlug[slug]; ok {
+			b, err := encodeNode(node)
+			return ".yaml", b, err
+		}
+	case configMapFormat:
+		data, err := yaml.Marshal(m)
+		if err != nil {
+			return "", nil, err
+		}
+		b, err := yaml.Marshal(configMap(r.AppDir+"-"+slug, map[string]string{"config.yaml": string(data)}))
+		return ".yaml", b, err
+	}
+
+	c, _ := codec.Lookup(format)
+	b, err := c.Encode(m)
+	return c.Extensions()[0], b, err
+}
+
+// configMapManifest is a Kubernetes ConfigMap
+type configMapManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   configMapMetadata `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type configMapMetadata struct {
+	Name string `yaml:"name"`
+}
+
+// configMap returns a ConfigMap named by name as a valid resource name (lower case alphanumerics, '-' and '.')
+func configMap(name string, data map[string]string) configMapManifest {
+	name = strings.Trim(configMapInvalidName.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	return configMapManifest{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   configMapMetadata{Name: name},
+		Data:       data,
+	}
+}
+
+var configMapInvalidName = regexp.MustCompile("[^a-z0-9.-]+")
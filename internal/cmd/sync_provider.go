@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/davidalpert/go-deep-merge/cfgset"
 	"github.com/davidalpert/go-deep-merge/internal/app"
-	"github.com/davidalpert/go-deep-merge/internal/cfgset"
 	"github.com/davidalpert/go-deep-merge/internal/provider"
 	"github.com/davidalpert/go-printers/v1"
 	"github.com/olekukonko/tablewriter"
@@ -79,7 +79,7 @@ type SyncKeyResult struct {
 
 // Run the command
 func (o *SyncProviderOptions) Run() error {
-	mergeResults, err := cfgset.Merge(o.MergeOptions)
+	mergeResults, err := cfgset.Merge(app.Fs, o.MergeOptions)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"fmt"
+	"reflect"
 )
 
 const DEFAULT_FIELD_KNOCKOUT_PREFIX = "--"
@@ -10,7 +11,8 @@ type Config struct {
 	// PreserveUnmergeables set to true to skip any unmergeable elements from source
 	PreserveUnmergeables bool
 
-	// KnockoutPrefix set to string value to signify prefix which deletes elements from existing element
+	// KnockoutPrefix set to string value to signify prefix which deletes elements from existing element;
+	// a hash key with this prefix (e.g. "--key") deletes the matching dest key and its subtree
 	KnockoutPrefix *string
 
 	// OverwriteArrays set to true if you want to avoid merging arrays
@@ -28,17 +30,62 @@ type Config struct {
 	// MergeHashArrays set to true to merge hashes within arrays
 	MergeHashArrays bool
 
+	// MergeHashArraysByKey set to a field name to merge hashes within arrays by matching on that field rather than by index
+	MergeHashArraysByKey *string
+
+	// MergeHashArraysByKeyAtPath set to match hashes within arrays by a field name only at paths matching a pattern
+	MergeHashArraysByKeyAtPath []PathKey
+
 	// KeepArrayDuplicates set to true to preserve duplicate array entries
 	KeepArrayDuplicates bool
 
+	// PathStrategies set to apply a Strategy to the values at (and below) paths matching a pattern; later matches win
+	PathStrategies []PathStrategy
+
+	// StrictTypes set to true to return a *MergeConflictError rather than overwrite or preserve a hash or array that is merged with a different type
+	StrictTypes bool
+
+	// CollectConflicts set to true to finish the merge and return every type conflict as a MergeConflictsError
+	CollectConflicts bool
+
+	// ConflictResolution set to resolve the conflicts found by Merge3WithOptions rather than fail with them (the default)
+	ConflictResolution Resolution
+
+	// DeepMergeKeys set to true to deep merge the hashes merged into a YAML mapping with a merge key (<<) rather than copy their top-level keys (see DecodeNode)
+	DeepMergeKeys bool
+
 	// MergeNilValues set to true to merge empty source values rather than skipping them (the default)
 	MergeNilValues bool
 
+	// DeleteNilValues set to true to delete the dest key for each nil source value (see NewConfigMergePatch)
+	DeleteNilValues bool
+
 	// Debug set to true to get console output of merge process for debugging
 	Debug bool
 
 	// DebugIndent set to customize indentation level of debug output
 	DebugIndent string
+
+	// path tracks the location of the current merge as deepMerge recurses
+	path Path
+
+	// provenance records the origin of each merged value when set (see WithProvenance)
+	provenance Provenance
+
+	// provenanceSource names the src map in provenance records
+	provenanceSource string
+
+	// conflicts collects type conflicts when CollectConflicts is set
+	conflicts *[]*MergeConflictError
+
+	// mergers are consulted in order before the built-in merge logic
+	mergers []mergerRegistration
+}
+
+// PathKey pairs a path pattern (see Path.Match) with the field name which identifies hashes within arrays at that path
+type PathKey struct {
+	Pattern string
+	Key     string
 }
 
 func NewConfig() *Config {
@@ -50,8 +97,14 @@ func NewConfig() *Config {
 		UnpackArrays:         nil,
 		ExtendExistingArrays: false,
 		MergeHashArrays:      false,
+		MergeHashArraysByKey: nil,
 		MergeNilValues:       false,
+		DeleteNilValues:      false,
 		KeepArrayDuplicates:  false,
+		StrictTypes:          false,
+		CollectConflicts:     false,
+		ConflictResolution:   ResolveFail,
+		DeepMergeKeys:        false,
 		Debug:                false,
 		DebugIndent:          "",
 	}
@@ -69,6 +122,13 @@ func NewConfigDeeperMerge() *Config {
 	return NewConfig().WithPreserveUnmergeables(true)
 }
 
+// NewConfigMergePatch follows JSON Merge Patch (RFC 7386) semantics: nil
+// source values delete dest keys, hashes merge recursively and arrays
+// and other values replace the dest value wholesale
+func NewConfigMergePatch() *Config {
+	return NewConfig().WithOverwriteUnmergeables(true).WithOverwriteArrays(true).WithDeleteNilValues(true)
+}
+
 func (c *Config) WithDebug(d bool) *Config {
 	c.Debug = d
 	return c
@@ -112,6 +172,72 @@ func (c *Config) WithMergeHashArrays(b bool) *Config {
 	return c
 }
 
+// WithMergeHashArraysByKey merges hashes within arrays by matching the value of the key field;
+// unmatched source hashes are appended and a knockout-prefixed key value removes the matching hash
+func (c *Config) WithMergeHashArraysByKey(key string) *Config {
+	c.MergeHashArraysByKey = &key
+	return c
+}
+
+// WithMergeHashArraysByKeyAtPath merges hashes within arrays by matching the value of the key field
+// only for arrays whose path matches pattern (e.g. "spec.containers" or "spec.containers[*].ports")
+func (c *Config) WithMergeHashArraysByKeyAtPath(pattern string, key string) *Config {
+	c.MergeHashArraysByKeyAtPath = append(c.MergeHashArraysByKeyAtPath, PathKey{Pattern: pattern, Key: key})
+	return c
+}
+
+// WithPathStrategy merges arrays at (and below) paths matching pattern (e.g. "spec.containers[*].args")
+// using the given Strategy rather than the options set on c
+func (c *Config) WithPathStrategy(pattern string, strategy Strategy) *Config {
+	c.PathStrategies = append(c.PathStrategies, PathStrategy{Pattern: pattern, Strategy: strategy})
+	return c
+}
+
+// WithProvenance records into p the origin of every value written by this merge, naming the src map source
+func (c *Config) WithProvenance(source string, p Provenance) *Config {
+	c.provenanceSource = source
+	c.provenance = p
+	return c
+}
+
+// WithMerger consults m before the built-in merge logic for every value
+func (c *Config) WithMerger(m Merger) *Config {
+	c.mergers = append(c.mergers, mergerRegistration{merger: m})
+	return c
+}
+
+// WithMergerForPath consults m before the built-in merge logic for values at paths matching pattern (see Path.Match)
+func (c *Config) WithMergerForPath(pattern string, m Merger) *Config {
+	c.mergers = append(c.mergers, mergerRegistration{pattern: &pattern, merger: m})
+	return c
+}
+
+// WithMergerForType consults m before the built-in merge logic when either the src or dest value has type t
+func (c *Config) WithMergerForType(t reflect.Type, m Merger) *Config {
+	c.mergers = append(c.mergers, mergerRegistration{typ: t, merger: m})
+	return c
+}
+
+func (c *Config) WithStrictTypes(b bool) *Config {
+	c.StrictTypes = b
+	return c
+}
+
+func (c *Config) WithCollectConflicts(b bool) *Config {
+	c.CollectConflicts = b
+	return c
+}
+
+func (c *Config) WithConflictResolution(r Resolution) *Config {
+	c.ConflictResolution = r
+	return c
+}
+
+func (c *Config) WithDeepMergeKeys(b bool) *Config {
+	c.DeepMergeKeys = b
+	return c
+}
+
 func (c *Config) WithKeepArrayDuplicates(b bool) *Config {
 	c.KeepArrayDuplicates = b
 	return c
@@ -127,6 +253,11 @@ func (c *Config) WithMergeNilValues(b bool) *Config {
 	return c
 }
 
+func (c *Config) WithDeleteNilValues(b bool) *Config {
+	c.DeleteNilValues = b
+	return c
+}
+
 func (c *Config) WithUnpackArrays(sep string) *Config {
 	c.UnpackArrays = &sep
 	return c
@@ -144,3 +275,63 @@ func (c *Config) copyWithIncreasedDebugIndent() *Config {
 	cc.DebugIndent = "  " + c.DebugIndent
 	return &cc
 }
+
+// copyWithoutTracking returns a copy of c for a separate merge which records neither provenance nor conflicts
+func (c *Config) copyWithoutTracking() *Config {
+	var cc = *c
+	cc.path = nil
+	cc.provenance = nil
+	cc.CollectConflicts = false
+	cc.conflicts = nil
+	return &cc
+}
+
+// copyForKey returns a copy of c for merging the value found under map key k
+func (c *Config) copyForKey(k string) *Config {
+	cc := c.copyWithIncreasedDebugIndent()
+	cc.path = c.path.Key(k)
+	return cc
+}
+
+// copyForIndex returns a copy of c for merging the value found at array index i
+//
+// provenance is not tracked inside arrays; the array is recorded as a single value instead
+func (c *Config) copyForIndex(i int) *Config {
+	cc := c.copyWithIncreasedDebugIndent()
+	cc.path = c.path.Index(i)
+	cc.provenance = nil
+	return cc
+}
+
+// copyForPathStrategies returns a copy of c with the strategies matching the current path applied,
+// or c itself when no strategies match
+func (c *Config) copyForPathStrategies() (*Config, error) {
+	cc := c
+	for _, ps := range c.PathStrategies {
+		if c.path.Match(ps.Pattern) {
+			if cc == c {
+				var copied = *c
+				cc = &copied
+			}
+			c.writeDebug("applying strategy %#v at %s", ps.Strategy, c.path)
+			if err := ps.Strategy.apply(cc); err != nil {
+				return nil, fmt.Errorf("%s: %v", c.path, err)
+			}
+		}
+	}
+	return cc, nil
+}
+
+// hashArrayKey returns the field name used to match hashes within the array at the current path
+func (c *Config) hashArrayKey() (string, bool) {
+	key, found := "", false
+	if c.MergeHashArraysByKey != nil {
+		key, found = *c.MergeHashArraysByKey, true
+	}
+	for _, pk := range c.MergeHashArraysByKeyAtPath {
+		if c.path.Match(pk.Pattern) {
+			key, found = pk.Key, true
+		}
+	}
+	return key, found
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeConflictError describes a src value which cannot be merged with
+// the dest value at Path because one is a hash or array and the other
+// is not (see Config.StrictTypes)
+type MergeConflictError struct {
+	Path      Path
+	SrcType   string
+	DestType  string
+	SrcValue  interface{}
+	DestValue interface{}
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("%s: cannot merge %s %#v into %s %#v", e.pathString(), e.SrcType, e.SrcValue, e.DestType, e.DestValue)
+}
+
+func (e *MergeConflictError) pathString() string {
+	if len(e.Path) == 0 {
+		return "<root>"
+	}
+	return e.Path.String()
+}
+
+// MergeConflictsError collects every MergeConflictError found by a merge
+// (see Config.CollectConflicts)
+type MergeConflictsError []*MergeConflictError
+
+func (e MergeConflictsError) Error() string {
+	lines := make([]string, len(e))
+	for i, c := range e {
+		lines[i] = "- " + c.Error()
+	}
+	return fmt.Sprintf("found %d merge conflict(s):\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// typeName describes v as a "hash", an "array", or by its scalar type
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "hash"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// checkConflict reports when src cannot be merged with dest and strict
+// type checking is enabled; conflicts are returned as an error right away
+// or, when collecting conflicts, saved for the end of the merge
+func (c *Config) checkConflict(src, dest interface{}) error {
+	if !c.StrictTypes && c.conflicts == nil {
+		return nil
+	}
+	if src == nil || dest == nil || c.isKnockout(src) {
+		return nil
+	}
+
+	srcType, destType := typeName(src), typeName(dest)
+	if srcType == destType || (!isHash(src) && !isArray(src) && !isHash(dest) && !isArray(dest)) {
+		return nil
+	}
+
+	err := &MergeConflictError{
+		Path:      c.path,
+		SrcType:   srcType,
+		DestType:  destType,
+		SrcValue:  src,
+		DestValue: dest,
+	}
+	if c.conflicts != nil {
+		c.writeDebug("conflict: %v", err)
+		*c.conflicts = append(*c.conflicts, err)
+		return nil
+	}
+	return err
+}
+
+// isKnockout returns true when v is a string starting with the knockout prefix
+func (c *Config) isKnockout(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && c.KnockoutPrefix != nil && strings.HasPrefix(s, *c.KnockoutPrefix)
+}
+
+func isArray(item interface{}) bool {
+	_, ok := item.([]interface{})
+	return ok
+}
@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStrictTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		dest    string
+		opt     *Config
+		want    string
+		wantErr *MergeConflictError
+	}{
+		{
+			name:    "hash over array",
+			src:     `{"property" => {"bedroom_count" => {"king_bed" => 3}}}`,
+			dest:    `{"property" => {"bedroom_count" => ["1", "2"]}}`,
+			opt:     NewConfigDeeperMergeBang().WithStrictTypes(true),
+			wantErr: &MergeConflictError{Path: Path{"property", "bedroom_count"}, SrcType: "hash", DestType: "array", SrcValue: map[string]interface{}{"king_bed": 3.0}, DestValue: []interface{}{"1", "2"}},
+		},
+		{
+			name:    "scalar over hash",
+			src:     `{"property" => "1"}`,
+			dest:    `{"property" => {"bedroom_count" => ["2"]}}`,
+			opt:     NewConfigDeeperMergeBang().WithStrictTypes(true),
+			wantErr: &MergeConflictError{Path: Path{"property"}, SrcType: "string", DestType: "hash", SrcValue: "1", DestValue: map[string]interface{}{"bedroom_count": []interface{}{"2"}}},
+		},
+		{
+			name:    "array over scalar",
+			src:     `{"property" => ["1"]}`,
+			dest:    `{"property" => "2"}`,
+			opt:     NewConfigDeeperMergeBang().WithStrictTypes(true).WithOverwriteArrays(true),
+			wantErr: &MergeConflictError{Path: Path{"property"}, SrcType: "array", DestType: "string", SrcValue: []interface{}{"1"}, DestValue: "2"},
+		},
+		{
+			name:    "scalar over array inside a hash array",
+			src:     `{"item" => [{"id" => "1"}]}`,
+			dest:    `{"item" => [{"id" => ["2"]}]}`,
+			opt:     NewConfigDeeperMergeBang().WithStrictTypes(true).WithMergeHashArrays(true),
+			wantErr: &MergeConflictError{Path: Path{"item", "[0]", "id"}, SrcType: "string", DestType: "array", SrcValue: "1", DestValue: []interface{}{"2"}},
+		},
+		{
+			name: "scalars of different types are not conflicts",
+			src:  `{"property" => 1}`,
+			dest: `{"property" => "2"}`,
+			opt:  NewConfigDeeperMergeBang().WithStrictTypes(true),
+			want: `{"property" => 1}`,
+		},
+		{
+			name: "knockouts are not conflicts",
+			src:  `{"property" => "--"}`,
+			dest: `{"property" => {"bedroom_count" => ["2"]}}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix().WithStrictTypes(true),
+			want: `{"property" => ""}`,
+		},
+		{
+			name: "new keys are not conflicts",
+			src:  `{"property" => {"bedroom_count" => ["2"]}}`,
+			dest: `{}`,
+			opt:  NewConfigDeeperMergeBang().WithStrictTypes(true),
+			want: `{"property" => {"bedroom_count" => ["2"]}}`,
+		},
+		{
+			name: "extending arrays is not a conflict",
+			src:  `{ "property" => "4" }`,
+			dest: `{ "property" => ["1", "2", "3"] }`,
+			opt:  NewConfigDeeperMergeBang().WithExtendExistingArrays(true).WithStrictTypes(true),
+			want: `{"property" => ["1", "2", "3", "4"]}`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			s, err := rubyHashToMap(tt.src)
+			assert.NoError(t, err, "unmarshall source >>%s<< to map: %v", tt.src, err)
+			d, err := rubyHashToMap(tt.dest)
+			assert.NoError(t, err, "unmarshall dest >>%s<< to map: %v", tt.dest, err)
+
+			// Act
+			got, err := MergeWithOptions(s, d, tt.opt)
+
+			// Assert
+			if tt.wantErr != nil {
+				var conflict *MergeConflictError
+				if assert.True(t, errors.As(err, &conflict), "MergeWithOptions() error = %v, want *MergeConflictError", err) {
+					assert.Equal(t, tt.wantErr, conflict)
+				}
+				return
+			}
+
+			assert.NoError(t, err, "MergeWithOptions()")
+			w, err := rubyHashToMap(tt.want)
+			assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+			assert.Equal(t, w, got, "Merge() got = %v, want %v", got, w)
+		})
+	}
+}
+
+func TestCollectConflicts(t *testing.T) {
+	s, err := rubyHashToMap(`{"a" => {"b" => "1"}, "c" => ["2"], "d" => "3", "e" => "ok"}`)
+	assert.NoError(t, err)
+	d, err := rubyHashToMap(`{"a" => ["1"], "c" => {"x" => "2"}, "d" => {"y" => "3"}, "e" => "before"}`)
+	assert.NoError(t, err)
+
+	_, err = MergeWithOptions(s, d, NewConfigDeeperMergeBang().WithCollectConflicts(true))
+
+	var conflicts MergeConflictsError
+	if assert.True(t, errors.As(err, &conflicts), "MergeWithOptions() error = %v, want MergeConflictsError", err) {
+		paths := make([]string, 0)
+		for _, c := range conflicts {
+			paths = append(paths, c.Path.String())
+		}
+		assert.ElementsMatch(t, []string{"a", "c", "d"}, paths)
+	}
+
+	_, err = MergeWithOptions(map[string]interface{}{"e": "ok"}, map[string]interface{}{"e": "before"}, NewConfigDeeperMergeBang().WithCollectConflicts(true))
+	assert.NoError(t, err, "no conflicts")
+}
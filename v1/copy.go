@@ -0,0 +1,96 @@
+package v1
+
+import "reflect"
+
+// DeepCopy returns a copy of v which shares no maps or slices with v so
+// that the copy can be merged into (or merged from) without side effects
+//
+// maps and slices are copied recursively; scalars (strings, numbers,
+// bools, nil) are immutable and returned as-is
+func DeepCopy(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return DeepCopyMap(vv)
+	case []interface{}:
+		return deepCopySlice(vv)
+	case map[interface{}]interface{}:
+		if vv == nil {
+			return vv
+		}
+		c := make(map[interface{}]interface{}, len(vv))
+		for k, item := range vv {
+			c[k] = DeepCopy(item)
+		}
+		return c
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return vv
+	default:
+		return deepCopyReflect(reflect.ValueOf(v)).Interface()
+	}
+}
+
+// DeepCopyMap returns a deep copy of m (see DeepCopy)
+func DeepCopyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = DeepCopy(v)
+	}
+	return c
+}
+
+// deepCopySlice returns a deep copy of s (see DeepCopy)
+func deepCopySlice(s []interface{}) []interface{} {
+	if s == nil {
+		return nil
+	}
+	c := make([]interface{}, len(s))
+	for i, v := range s {
+		c[i] = DeepCopy(v)
+	}
+	return c
+}
+
+// deepCopyReflect copies typed maps and slices (e.g. []string or
+// map[string]int) which do not match the generic cases in DeepCopy
+func deepCopyReflect(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopyValue(iter.Value(), v.Type().Elem()))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i), v.Type().Elem()))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// deepCopyValue copies a map value or slice element and converts it back
+// to the element type t of its container
+func deepCopyValue(v reflect.Value, t reflect.Type) reflect.Value {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		return reflect.ValueOf(DeepCopy(v.Interface()))
+	}
+	return deepCopyReflect(v)
+}
@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestDeepCopy(t *testing.T) {
+	tests := []struct {
+		name string
+		have interface{}
+	}{
+		{name: "nil", have: nil},
+		{name: "string", have: "value"},
+		{name: "number", have: 3.0},
+		{name: "map", have: map[string]interface{}{"id": "2", "region": map[string]interface{}{"ids": []interface{}{"227", "233"}}}},
+		{name: "slice of maps", have: []interface{}{map[string]interface{}{"1": "3"}, map[string]interface{}{"2": []interface{}{4}}}},
+		{name: "typed slice", have: []string{"a", "b"}},
+		{name: "typed map", have: map[string][]int{"a": {1, 2}}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			got := DeepCopy(tt.have)
+			assert.Equal(t, tt.have, got, "DeepCopy()")
+		})
+	}
+}
+
+func TestDeepCopyDoesNotShareState(t *testing.T) {
+	original, err := rubyHashToMap(`{"region" => {"ids" => ["227", "233"], "id" => "230"}, "item" => [{"1" => "3"}]}`)
+	assert.NoError(t, err)
+
+	c := DeepCopyMap(original)
+	c["region"].(map[string]interface{})["id"] = "999"
+	c["region"].(map[string]interface{})["ids"].([]interface{})[0] = "999"
+	c["item"].([]interface{})[0].(map[string]interface{})["1"] = "999"
+
+	want, err := rubyHashToMap(`{"region" => {"ids" => ["227", "233"], "id" => "230"}, "item" => [{"1" => "3"}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, want, original, "DeepCopyMap() copy shares state with the original")
+}
+
+// TestMergeCopy verifies that neither input is modified by the merge
+func TestMergeCopy(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		dest string
+		opt  *Config
+		want string
+	}{
+		{
+			name: "hashes holding array",
+			src:  `{"property" => ["1","3"]}`,
+			dest: `{"property" => ["2","4"]}`,
+			opt:  NewConfigDeeperMergeBang(),
+			want: `{"property" => ["2","4","1","3"]}`,
+		},
+		{
+			name: "new keys holding hashes",
+			src:  `{"property" => {"bedroom_count" => {"2"=>3, "king_bed" => [3]}, "bathroom_count" => ["1"]}}`,
+			dest: `{}`,
+			opt:  NewConfigDeeperMergeBang(),
+			want: `{"property" => {"bedroom_count" => {"2"=>3, "king_bed" => [3]}, "bathroom_count" => ["1"]}}`,
+		},
+		{
+			name: "merge hash arrays",
+			src:  `{"item" => [{"1" => "3"}, {"2" => "4"}]}`,
+			dest: `{"item" => [{"3" => "5"}]}`,
+			opt:  NewConfigDeeperMergeBang().WithMergeHashArrays(true),
+			want: `{"item" => [{"3" => "5", "1" => "3"}, {"2" => "4"}]}`,
+		},
+		{
+			name: "knockout",
+			src:  `{"region" => {'ids' => ["2", "--", "6"]}}`,
+			dest: `{"region"=>{"ids"=>["1", "2", "3", "4"], 'id'=>'11'}}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix().WithUnpackArrays(","),
+			want: `{'region' => {'ids' => ["2", "6"], 'id'=>'11'}}`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			s, err := rubyHashToMap(tt.src)
+			assert.NoError(t, err, "unmarshall source >>%s<< to map: %v", tt.src, err)
+			d, err := rubyHashToMap(tt.dest)
+			assert.NoError(t, err, "unmarshall dest >>%s<< to map: %v", tt.dest, err)
+			s0 := DeepCopyMap(s)
+			d0 := DeepCopyMap(d)
+
+			// Act
+			got, err := MergeCopyWithOptions(s, d, tt.opt)
+
+			// Assert
+			assert.NoError(t, err, "MergeCopyWithOptions()")
+			w, err := rubyHashToMap(tt.want)
+			assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+			assert.Equal(t, w, got, "MergeCopyWithOptions() got = %v, want %v", got, w)
+			assert.Equal(t, s0, s, "MergeCopyWithOptions() modified src")
+			assert.Equal(t, d0, d, "MergeCopyWithOptions() modified dest")
+		})
+	}
+}
+
+// TestMergeCopyConcurrently reuses one parsed base config across many concurrent merges
+func TestMergeCopyConcurrently(t *testing.T) {
+	base, err := rubyHashToMap(`{"log_level": "WARN", "env": "REQUIRED", "auth": { "username": "default_user", "password": "default_pass" }, "hosts": ["a"] }`)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([]map[string]interface{}, 50)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			src := map[string]interface{}{
+				"env":   fmt.Sprintf("env%d", i),
+				"auth":  map[string]interface{}{"password": fmt.Sprintf("pass%d", i)},
+				"hosts": []interface{}{fmt.Sprintf("h%d", i)},
+			}
+			r, err := MergeCopyWithOptions(src, base, NewConfigDeeperMergeBang().WithMergeHashArrays(true))
+			assert.NoError(t, err)
+			results[i] = r
+		}(i)
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		assert.Equal(t, fmt.Sprintf("env%d", i), r["env"])
+		assert.Equal(t, map[string]interface{}{"username": "default_user", "password": fmt.Sprintf("pass%d", i)}, r["auth"])
+		assert.Equal(t, []interface{}{"a", fmt.Sprintf("h%d", i)}, r["hosts"])
+	}
+
+	want, err := rubyHashToMap(`{"log_level": "WARN", "env": "REQUIRED", "auth": { "username": "default_user", "password": "default_pass" }, "hosts": ["a"] }`)
+	assert.NoError(t, err)
+	assert.Equal(t, want, base, "base config was modified")
+}
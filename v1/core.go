@@ -2,6 +2,7 @@ package v1
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 )
@@ -13,11 +14,23 @@ func Merge(src, dest map[string]interface{}) (map[string]interface{}, error) {
 
 // MergeWithOptions deep merges the src map into dest map with the given options and returns a new map of merged values
 func MergeWithOptions(src, dest map[string]interface{}, options *Config) (map[string]interface{}, error) {
+	if options.CollectConflicts {
+		var o = *options
+		o.conflicts = &[]*MergeConflictError{}
+		options = &o
+	}
+
 	result, err := deepMerge(src, dest, options)
 	if err != nil {
 		return nil, err
 	}
 
+	if options.conflicts != nil && len(*options.conflicts) > 0 {
+		conflicts := MergeConflictsError(*options.conflicts)
+		sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Path.String() < conflicts[j].Path.String() })
+		return nil, conflicts
+	}
+
 	switch v := result.(type) {
 	case map[string]interface{}:
 		return v, nil
@@ -26,6 +39,27 @@ func MergeWithOptions(src, dest map[string]interface{}, options *Config) (map[st
 	}
 }
 
+// MergeCopy deep merges a copy of the src map into a copy of the dest map with the default options and returns a new map of merged values
+//
+// unlike Merge neither src nor dest are modified so a parsed base config can be reused across many merges
+func MergeCopy(src, dest map[string]interface{}) (map[string]interface{}, error) {
+	return MergeCopyWithOptions(src, dest, NewConfig())
+}
+
+// MergeCopyWithOptions deep merges a copy of the src map into a copy of the dest map with the given options and returns a new map of merged values
+//
+// unlike MergeWithOptions neither src nor dest are modified so a parsed base config can be reused across many merges
+func MergeCopyWithOptions(src, dest map[string]interface{}, options *Config) (map[string]interface{}, error) {
+	return MergeWithOptions(DeepCopyMap(src), DeepCopyMap(dest), options)
+}
+
+// MergePatch applies patch to target following JSON Merge Patch (RFC 7386) semantics and returns the patched map
+//
+// a nil value in patch deletes the key from target (e.g. "key: ~" in YAML or "key": null in JSON)
+func MergePatch(patch, target map[string]interface{}) (map[string]interface{}, error) {
+	return MergeWithOptions(patch, target, NewConfigMergePatch())
+}
+
 // deepMerge is a recursive function ported from the ruby deep_merge library
 func deepMerge(src, dest interface{}, o *Config) (interface{}, error) {
 	overwriteUnmergeable := !o.PreserveUnmergeables
@@ -44,57 +78,106 @@ func deepMerge(src, dest interface{}, o *Config) (interface{}, error) {
 
 	//o.writeDebug("Source: %T :: Dest: %T", src, dest)
 
+	o, err := o.copyForPathStrategies()
+	if err != nil {
+		return nil, err
+	}
+
+	o.writeDebug("%#v", o)
+	if r, handled, err := o.customMerge(src, dest); err != nil {
+		return nil, err
+	} else if handled {
+		return r, nil
+	}
+
 	if !o.MergeNilValues && src == nil {
 		return dest, nil
 	}
 	if dest == nil && !o.PreserveUnmergeables {
+		if !isHash(src) && !isArray(src) {
+			// a knockout value merged onto nothing leaves nothing
+			return overwriteUnmergeables(src, dest, o)
+		}
 		return src, nil
 	}
 
-	o.writeDebug("%#v", o)
 	switch s := src.(type) {
 	case map[string]interface{}:
 		o.writeDebug("Hashes: %#v :: %#v", s, dest)
 		switch d := dest.(type) {
 		case map[string]interface{}:
+			if sameHash(s, d) {
+				// a hash merged with itself (e.g. within an array copied over) is copied into a new hash so src is left unchanged
+				d = make(map[string]interface{})
+			}
+			if o.KnockoutPrefix != nil {
+				// knock out dest keys first so that a src hash can remove a key and provide a replacement for it
+				for sk := range s {
+					if key := strings.TrimPrefix(sk, *o.KnockoutPrefix); key != sk && key != "" {
+						if _, ok := d[key]; ok {
+							o.writeDebug(" ==>knocking out key: %#v :: %#v", key, d)
+							delete(d, key)
+							o.recordDeletion(o.path.Key(key))
+						}
+					}
+				}
+			}
 			for sk, sv := range s {
-				if _, ok := d[sk]; ok {
+				if o.KnockoutPrefix != nil && strings.HasPrefix(sk, *o.KnockoutPrefix) && sk != *o.KnockoutPrefix {
+					continue
+				}
+				if sv == nil && o.DeleteNilValues {
+					if _, ok := d[sk]; ok {
+						o.writeDebug(" ==>deleting: %#v :: %#v", sk, d)
+						delete(d, sk)
+						o.recordDeletion(o.path.Key(sk))
+					}
+					continue
+				}
+				if prev, ok := d[sk]; ok {
 					o.writeDebug(" ==>merging: %#v => %#v :: %#v", sk, sv, d)
-					r, err := deepMerge(sv, d[sk], o.copyWithIncreasedDebugIndent())
+					if o.provenance != nil {
+						// snapshot prev as hashes (and hashes in arrays) are merged in place
+						prev = DeepCopy(prev)
+					}
+					r, err := deepMerge(sv, d[sk], o.copyForKey(sk))
 					if err != nil {
 						return nil, err
 					}
 					d[sk] = r
+					o.recordMerge(o.path.Key(sk), sv, prev, true, r)
 				} else {
 					o.writeDebug(" ==>copying over: %#v => %#v :: %#v", sk, sv, d)
 					// dest[src_key] doesn't exist so we want to create and overwrite it (but we do this via deep_merge!)
-					// note: we rescue here b/c some classes respond to "dup" but don't implement it (Numeric, TrueClass, FalseClass, NilClass among maybe others)
-					// we dup src_value if possible because we're going to merge into it (since dest is empty)
-					// TODO: duplicate values safely
+					// src_value is merged into a new value rather than into itself so that src is left unchanged
+					// the strategies for the key decide how its array is copied
+					ko, err := o.copyForKey(sk).copyForPathStrategies()
+					if err != nil {
+						return nil, err
+					}
+					var r interface{}
 					switch src_dup := sv.(type) {
 					case []interface{}:
-						if o.KeepArrayDuplicates {
+						if ko.KeepArrayDuplicates {
 							// note: in this case the merge will be additive, rather than a bounded set, so we can't simply merge src with itself
 							// We need to merge src with an empty array
-							if r, err := deepMerge(sv, make([]interface{}, 0), o.copyWithIncreasedDebugIndent()); err != nil {
-								return nil, err
-							} else {
-								d[sk] = r
-							}
+							r, err = deepMerge(sv, make([]interface{}, 0), o.copyForKey(sk))
 						} else {
-							r, err := deepMerge(sv, src_dup, o.copyWithIncreasedDebugIndent())
-							if err != nil {
-								return nil, err
-							}
-							d[sk] = r
+							r, err = deepMerge(sv, append(make([]interface{}, 0, len(src_dup)), src_dup...), o.copyForKey(sk))
 						}
+					case map[string]interface{}:
+						r, err = deepMerge(sv, make(map[string]interface{}), o.copyForKey(sk))
 					default:
-						r, err := deepMerge(sv, src_dup, o.copyWithIncreasedDebugIndent())
-						if err != nil {
-							return nil, err
-						}
-						d[sk] = r
+						// a Merger sees nil for the missing dest; there is nothing there to preserve
+						kc := o.copyForKey(sk)
+						kc.PreserveUnmergeables = false
+						r, err = deepMerge(sv, nil, kc)
 					}
+					if err != nil {
+						return nil, err
+					}
+					d[sk] = r
+					o.recordMerge(o.path.Key(sk), sv, nil, false, d[sk])
 				}
 			}
 			return d, nil
@@ -103,11 +186,25 @@ func deepMerge(src, dest interface{}, o *Config) (interface{}, error) {
 				d = append(d, s)
 				return d, nil
 			}
+			if err := o.checkConflict(s, d); err != nil {
+				return nil, err
+			}
+			if o.DeleteNilValues && !o.PreserveUnmergeables {
+				// merge into an empty hash so that nil values are dropped from src
+				return deepMerge(s, make(map[string]interface{}), o)
+			}
 			if !o.PreserveUnmergeables {
 				return overwriteUnmergeables(s, d, o)
 			}
 			return d, nil
 		default: // else dest isn't a hash, so we overwrite it completely (if permitted)
+			if err := o.checkConflict(s, d); err != nil {
+				return nil, err
+			}
+			if o.DeleteNilValues && !o.PreserveUnmergeables {
+				// merge into an empty hash so that nil values are dropped from src
+				return deepMerge(s, make(map[string]interface{}), o)
+			}
 			if !o.PreserveUnmergeables {
 				return overwriteUnmergeables(s, d, o)
 			}
@@ -116,6 +213,11 @@ func deepMerge(src, dest interface{}, o *Config) (interface{}, error) {
 		}
 	case []interface{}:
 		o.writeDebug("Arrays: %#v :: %#v", s, dest)
+		if !isArray(dest) {
+			if err := o.checkConflict(s, dest); err != nil {
+				return nil, err
+			}
+		}
 		if o.OverwriteArrays {
 			o.writeDebug("> overwrite arrays")
 			return src, nil
@@ -179,14 +281,21 @@ func deepMerge(src, dest interface{}, o *Config) (interface{}, error) {
 				sourceAllHashes := allHashes(s)
 				destAllHashes := allHashes(d)
 
-				if o.MergeHashArrays && sourceAllHashes && destAllHashes {
+				if key, found := o.hashArrayKey(); found && sourceAllHashes && destAllHashes {
+					o.writeDebug("merge hashes in lists by key %#v", key)
+					if r, err := mergeHashArraysByKey(s, d, key, o); err != nil {
+						return nil, err
+					} else {
+						d = r
+					}
+				} else if o.MergeHashArrays && sourceAllHashes && destAllHashes {
 					o.writeDebug("merge hashes in lists")
 					list := make([]interface{}, 0)
 					for i, dv := range d {
 						if i < len(s) {
 							sv := s[i]
 							o.writeDebug("- index %d: %#v :: %#v", i, sv, dv)
-							if r, err := deepMerge(sv, dv, o.copyWithIncreasedDebugIndent()); err != nil {
+							if r, err := deepMerge(sv, dv, o.copyForIndex(i)); err != nil {
 								return nil, err
 							} else {
 								list = append(list, r)
@@ -242,13 +351,81 @@ func deepMerge(src, dest interface{}, o *Config) (interface{}, error) {
 				d = append(d, s)
 				return d, nil
 			}
+			if err := o.checkConflict(s, d); err != nil {
+				return nil, err
+			}
 			return overwriteUnmergeables(s, d, o)
 		default:
+			if err := o.checkConflict(s, d); err != nil {
+				return nil, err
+			}
 			return overwriteUnmergeables(s, d, o)
 		}
 	}
 }
 
+// mergeHashArraysByKey merges the hashes in s into the hashes in d which share the same value for key
+//
+// source hashes without a match are appended; a source hash whose key value starts with the knockout
+// prefix removes the matching dest hash instead
+func mergeHashArraysByKey(s []interface{}, d []interface{}, key string, o *Config) ([]interface{}, error) {
+	list := make([]interface{}, 0, len(d))
+	for _, dv := range d {
+		// knockout hashes only reach dest when src is copied over by merging it with itself
+		if id, ok := dv.(map[string]interface{})[key]; ok && o.KnockoutPrefix != nil && strings.HasPrefix(fmt.Sprintf("%v", id), *o.KnockoutPrefix) {
+			continue
+		}
+		list = append(list, dv)
+	}
+
+	indexByID := make(map[string]int)
+	for i, dv := range list {
+		if id, ok := dv.(map[string]interface{})[key]; ok {
+			indexByID[fmt.Sprintf("%v", id)] = i
+		}
+	}
+
+	removed := make(map[int]bool)
+	for _, sv := range s {
+		id, ok := sv.(map[string]interface{})[key]
+		if !ok {
+			o.writeDebug("- no %#v in %#v; appending", key, sv)
+			list = append(list, sv)
+			continue
+		}
+		idString := fmt.Sprintf("%v", id)
+		if o.KnockoutPrefix != nil && strings.HasPrefix(idString, *o.KnockoutPrefix) {
+			idString = strings.TrimPrefix(idString, *o.KnockoutPrefix)
+			if i, ok := indexByID[idString]; ok {
+				o.writeDebug("- %s %#v: knocking out %#v", key, idString, list[i])
+				removed[i] = true
+				delete(indexByID, idString)
+			}
+			continue
+		}
+		if i, ok := indexByID[idString]; ok {
+			o.writeDebug("- %s %#v: %#v :: %#v", key, idString, sv, list[i])
+			r, err := deepMerge(sv, list[i], o.copyForIndex(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = r
+		} else {
+			o.writeDebug("- %s %#v: appending %#v", key, idString, sv)
+			indexByID[idString] = len(list)
+			list = append(list, sv)
+		}
+	}
+
+	result := make([]interface{}, 0, len(list))
+	for i, v := range list {
+		if !removed[i] {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
 // [3, 4, 5] ==> [1, 2, 3] = [1, 2, 3, 4, 5]
 func combineWithoutDuplicates(s []interface{}, d []interface{}, o *Config) []interface{} {
 	for _, v := range s {
@@ -282,6 +459,11 @@ func allHashes(items []interface{}) bool {
 	return sliceOfAll(items, isHash)
 }
 
+// sameHash returns true when a and b are the same map rather than two maps with equal contents
+func sameHash(a, b map[string]interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
 func isHash(item interface{}) bool {
 	switch item.(type) {
 	case map[string]interface{}:
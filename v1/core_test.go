@@ -1111,3 +1111,354 @@ func TestKeepArrayDuplicates(t *testing.T) {
 		})
 	}
 }
+
+// TestMergeHashArraysByKey contains tests for merging hashes within arrays by an identity field
+func TestMergeHashArraysByKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		dest    string
+		opt     *Config
+		want    string
+		wantErr bool
+	}{
+		{
+			name: `hashes are matched by key regardless of order`,
+			src:  `{"containers" => [{"name" => "sidecar", "image" => "proxy:2"}, {"name" => "web", "image" => "web:2"}]}`,
+			dest: `{"containers" => [{"name" => "web", "image" => "web:1", "port" => 80}, {"name" => "sidecar", "image" => "proxy:1"}]}`,
+			opt:  NewConfigDeeperMergeBang().WithMergeHashArraysByKey("name"),
+			want: `{"containers" => [{"name" => "web", "image" => "web:2", "port" => 80}, {"name" => "sidecar", "image" => "proxy:2"}]}`,
+		},
+		{
+			name: `unmatched source hashes are appended`,
+			src:  `{"containers" => [{"name" => "worker", "image" => "worker:1"}, {"image" => "anonymous:1"}]}`,
+			dest: `{"containers" => [{"name" => "web", "image" => "web:1"}]}`,
+			opt:  NewConfigDeeperMergeBang().WithMergeHashArraysByKey("name"),
+			want: `{"containers" => [{"name" => "web", "image" => "web:1"}, {"name" => "worker", "image" => "worker:1"}, {"image" => "anonymous:1"}]}`,
+		},
+		{
+			name: `knockout prefix on the key value removes the matching hash`,
+			src:  `{"containers" => [{"name" => "--sidecar"}, {"name" => "web", "image" => "web:2"}]}`,
+			dest: `{"containers" => [{"name" => "web", "image" => "web:1"}, {"name" => "sidecar", "image" => "proxy:1"}]}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix().WithMergeHashArraysByKey("name"),
+			want: `{"containers" => [{"name" => "web", "image" => "web:2"}]}`,
+		},
+		{
+			name: `knockout hashes are dropped from an array missing from dest`,
+			src:  `{"c" => [{"name" => "--a"}, {"name" => "z"}]}`,
+			dest: `{}`,
+			opt:  NewConfigDeeperMergeKO().WithMergeHashArraysByKey("name"),
+			want: `{"c" => [{"name" => "z"}]}`,
+		},
+		{
+			name: `matched hashes are deep merged`,
+			src:  `{"containers" => [{"name" => "web", "env" => {"LOG_LEVEL" => "DEBUG"}, "args" => ["--verbose"]}]}`,
+			dest: `{"containers" => [{"name" => "web", "env" => {"PORT" => "80"}, "args" => ["serve"]}]}`,
+			opt:  NewConfigDeeperMergeBang().WithMergeHashArraysByKey("name"),
+			want: `{"containers" => [{"name" => "web", "env" => {"PORT" => "80", "LOG_LEVEL" => "DEBUG"}, "args" => ["serve", "--verbose"]}]}`,
+		},
+		{
+			name: `key at path only applies to matching arrays`,
+			src:  `{"containers" => [{"name" => "web", "image" => "web:2"}], "upstreams" => [{"name" => "b"}]}`,
+			dest: `{"containers" => [{"name" => "web", "image" => "web:1"}], "upstreams" => [{"name" => "a"}]}`,
+			opt:  NewConfigDeeperMergeBang().WithMergeHashArraysByKeyAtPath("containers", "name"),
+			want: `{"containers" => [{"name" => "web", "image" => "web:2"}], "upstreams" => [{"name" => "a"}, {"name" => "b"}]}`,
+		},
+		{
+			name: `key at nested path with array wildcard`,
+			src:  `{"spec" => {"containers" => [{"name" => "web", "ports" => [{"name" => "http", "port" => 8080}]}]}}`,
+			dest: `{"spec" => {"containers" => [{"name" => "web", "ports" => [{"name" => "metrics", "port" => 9090}, {"name" => "http", "port" => 80}]}]}}`,
+			opt: NewConfigDeeperMergeBang().
+				WithMergeHashArraysByKeyAtPath("spec.containers", "name").
+				WithMergeHashArraysByKeyAtPath("spec.containers[*].ports", "name"),
+			want: `{"spec" => {"containers" => [{"name" => "web", "ports" => [{"name" => "metrics", "port" => 9090}, {"name" => "http", "port" => 8080}]}]}}`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			s, err := rubyHashToMap(tt.src)
+			assert.NoError(t, err, "unmarshall source >>%s<< to map: %v", tt.src, err)
+			d, err := rubyHashToMap(tt.dest)
+			assert.NoError(t, err, "unmarshall dest >>%s<< to map: %v", tt.dest, err)
+
+			// Act
+			got, err := MergeWithOptions(s, d, tt.opt)
+
+			// Assert
+			if (err != nil) != tt.wantErr {
+				assert.FailNow(t, "Merge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.want != "" {
+				w, err := rubyHashToMap(tt.want)
+				assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+				assert.Equal(t, w, got, "Merge() got = %v, want %v", got, w)
+			}
+		})
+	}
+}
+
+// TestPathStrategies contains tests for attaching merge strategies to paths
+func TestPathStrategies(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		dest    string
+		opt     *Config
+		want    string
+		wantErr bool
+	}{
+		{
+			name: `one merge can union some lists and replace others`,
+			src:  `{"features" => ["b", "a"], "hosts" => ["c"]}`,
+			dest: `{"features" => ["d", "c"], "hosts" => ["a", "b"]}`,
+			opt:  NewConfigDeeperMergeBang().WithPathStrategy("features", StrategyUnionSorted).WithPathStrategy("hosts", StrategyReplace),
+			want: `{"features" => ["a", "b", "c", "d"], "hosts" => ["c"]}`,
+		},
+		{
+			name: `strategy at a wildcard path`,
+			src:  `{"spec" => {"containers" => [{"name" => "web", "args" => ["--port", "8080"], "env" => ["B"]}]}}`,
+			dest: `{"spec" => {"containers" => [{"name" => "web", "args" => ["serve"], "env" => ["A"]}]}}`,
+			opt: NewConfigDeeperMergeBang().
+				WithPathStrategy("spec.containers", StrategyMergeByKey("name")).
+				WithPathStrategy("spec.containers[*].args", StrategyReplace),
+			want: `{"spec" => {"containers" => [{"name" => "web", "args" => ["--port", "8080"], "env" => ["A", "B"]}]}}`,
+		},
+		{
+			name: `strategy applies below the matching path`,
+			src:  `{"a" => {"list" => ["1"]}, "b" => {"list" => ["1"]}}`,
+			dest: `{"a" => {"list" => ["1"]}, "b" => {"list" => ["1"]}}`,
+			opt:  NewConfigDeeperMergeBang().WithPathStrategy("a", StrategyAppend),
+			want: `{"a" => {"list" => ["1", "1"]}, "b" => {"list" => ["1"]}}`,
+		},
+		{
+			name: `later strategies win`,
+			src:  `{"list" => ["1"]}`,
+			dest: `{"list" => ["2"]}`,
+			opt:  NewConfigDeeperMergeBang().WithPathStrategy("**", StrategyReplace).WithPathStrategy("list", StrategyUnion),
+			want: `{"list" => ["2", "1"]}`,
+		},
+		{
+			name: `a strategy overrides the global options`,
+			src:  `{"list" => ["1"], "other" => ["1"]}`,
+			dest: `{"list" => ["2"], "other" => ["2"]}`,
+			opt:  NewConfigDeeperMergeBang().WithOverwriteArrays(true).WithPathStrategy("list", StrategyUnion),
+			want: `{"list" => ["2", "1"], "other" => ["1"]}`,
+		},
+		{
+			name: `a strategy applies to a key missing from dest`,
+			src:  `{"features" => ["a", "b"]}`,
+			dest: `{}`,
+			opt:  NewConfigDeeperMergeBang().WithPathStrategy("features", StrategyAppend),
+			want: `{"features" => ["a", "b"]}`,
+		},
+		{
+			name: `a strategy applies to a nested key missing from dest`,
+			src:  `{"x" => {"features" => ["a"], "other" => ["b"]}}`,
+			dest: `{}`,
+			opt:  NewConfigDeeperMergeBang().WithPathStrategy("x.features", StrategyAppend),
+			want: `{"x" => {"features" => ["a"], "other" => ["b"]}}`,
+		},
+		{
+			name:    `unknown strategies are rejected`,
+			src:     `{"list" => ["1"]}`,
+			dest:    `{"list" => ["2"]}`,
+			opt:     NewConfigDeeperMergeBang().WithPathStrategy("list", Strategy("bogus")),
+			wantErr: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			s, err := rubyHashToMap(tt.src)
+			assert.NoError(t, err, "unmarshall source >>%s<< to map: %v", tt.src, err)
+			d, err := rubyHashToMap(tt.dest)
+			assert.NoError(t, err, "unmarshall dest >>%s<< to map: %v", tt.dest, err)
+
+			// Act
+			got, err := MergeWithOptions(s, d, tt.opt)
+
+			// Assert
+			if (err != nil) != tt.wantErr {
+				assert.FailNow(t, "Merge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.want != "" {
+				w, err := rubyHashToMap(tt.want)
+				assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+				assert.Equal(t, w, got, "Merge() got = %v, want %v", got, w)
+			}
+		})
+	}
+}
+
+// TestKnockoutHashKeys contains tests for removing dest hash keys with knockout-prefixed src keys
+func TestKnockoutHashKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		dest    string
+		opt     *Config
+		want    string
+		wantErr bool
+	}{
+		{
+			name: `knockout key removes the dest key`,
+			src:  `{"--amenity" => "", "id" => "2"}`,
+			dest: `{"amenity" => "1", "id" => "1"}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix(),
+			want: `{"id" => "2"}`,
+		},
+		{
+			name: `knockout key removes the dest subtree`,
+			src:  `{"region" => {"--ids" => nil}}`,
+			dest: `{"region"=>{"ids"=>["1", "2", "3", "4"], 'id'=>'11', "muni" => {"city_id" => "2244"}}}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix(),
+			want: `{"region"=>{'id'=>'11', "muni" => {"city_id" => "2244"}}}`,
+		},
+		{
+			name: `knockout key for a missing dest key is ignored`,
+			src:  `{"--amenity" => "1"}`,
+			dest: `{"id" => "1"}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix(),
+			want: `{"id" => "1"}`,
+		},
+		{
+			name: `knockout key and replacement replaces the dest subtree`,
+			src:  `{"--region" => nil, "region" => {"id" => "12"}}`,
+			dest: `{"region"=>{"ids"=>["1", "2"], "id"=>"11"}}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix(),
+			want: `{"region"=>{"id"=>"12"}}`,
+		},
+		{
+			name: `knockout keys are dropped from new hashes`,
+			src:  `{"region" => {"--ids" => nil, "id" => "12"}}`,
+			dest: `{}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix(),
+			want: `{"region"=>{"id"=>"12"}}`,
+		},
+		{
+			name: `keys with the knockout prefix are merged as-is without a knockout prefix`,
+			src:  `{"--amenity" => "1"}`,
+			dest: `{"amenity" => "1"}`,
+			opt:  NewConfigDeeperMergeBang(),
+			want: `{"--amenity" => "1", "amenity" => "1"}`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			s, err := rubyHashToMap(tt.src)
+			assert.NoError(t, err, "unmarshall source >>%s<< to map: %v", tt.src, err)
+			d, err := rubyHashToMap(tt.dest)
+			assert.NoError(t, err, "unmarshall dest >>%s<< to map: %v", tt.dest, err)
+
+			// Act
+			got, err := MergeWithOptions(s, d, tt.opt)
+
+			// Assert
+			if (err != nil) != tt.wantErr {
+				assert.FailNow(t, "Merge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.want != "" {
+				w, err := rubyHashToMap(tt.want)
+				assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+				assert.Equal(t, w, got, "Merge() got = %v, want %v", got, w)
+			}
+		})
+	}
+}
+
+// TestMergeLeavesSrcUnchanged checks that copying over keys missing from dest does not edit src
+func TestMergeLeavesSrcUnchanged(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		dest string
+		opt  *Config
+		want string
+	}{
+		{
+			name: `knockout key in a new hash`,
+			src:  `{"new" => {"--x" => "", "a" => 1}}`,
+			dest: `{}`,
+			opt:  NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix(),
+			want: `{"new" => {"a" => 1}}`,
+		},
+		{
+			name: `nil value in a new hash`,
+			src:  `{"new" => {"gone" => nil, "a" => 1}}`,
+			dest: `{}`,
+			opt:  NewConfig().WithDeleteNilValues(true),
+			want: `{"new" => {"a" => 1}}`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			s, err := rubyHashToMap(tt.src)
+			assert.NoError(t, err, "unmarshall source >>%s<< to map: %v", tt.src, err)
+			d, err := rubyHashToMap(tt.dest)
+			assert.NoError(t, err, "unmarshall dest >>%s<< to map: %v", tt.dest, err)
+			w, err := rubyHashToMap(tt.want)
+			assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+			original, err := rubyHashToMap(tt.src)
+			assert.NoError(t, err, "unmarshall source >>%s<< to map: %v", tt.src, err)
+
+			// Act
+			got, err := MergeWithOptions(s, d, tt.opt)
+
+			// Assert
+			assert.NoError(t, err, "Merge()")
+			assert.Equal(t, w, got, "Merge() got = %v, want %v", got, w)
+			assert.Equal(t, original, s, "Merge() changed src")
+		})
+	}
+}
+
+// TestMergePatch contains the examples from appendix A of RFC 7386
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{"a":"foo"}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"a":[1,2]}`, `{"a":[null]}`, `{"a":[null]}`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.patch), func(t *testing.T) {
+			// Arrange
+			target, err := UnmarshallJSONToMap(tt.target)
+			assert.NoError(t, err, "unmarshall target >>%s<< to map: %v", tt.target, err)
+			patch, err := UnmarshallJSONToMap(tt.patch)
+			assert.NoError(t, err, "unmarshall patch >>%s<< to map: %v", tt.patch, err)
+			w, err := UnmarshallJSONToMap(tt.want)
+			assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+
+			// Act
+			got, err := MergePatch(patch, target)
+
+			// Assert
+			assert.NoError(t, err, "MergePatch()")
+			assert.Equal(t, w, got, "MergePatch() got = %v, want %v", got, w)
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package v1
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ChangeType describes how a value differs between two config trees
+type ChangeType string
+
+const (
+	// ChangeAdded marks a value found only in the second tree
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved marks a value found only in the first tree
+	ChangeRemoved ChangeType = "removed"
+	// ChangeChanged marks a value which differs between the trees
+	ChangeChanged ChangeType = "changed"
+	// ChangeTypeChanged marks a value whose type (hash, array, string, number, ...) differs between the trees
+	ChangeTypeChanged ChangeType = "type-changed"
+)
+
+// Change is a single difference found by Diff
+//
+// From is the value in the first tree (nil when added) and To is the
+// value in the second tree (nil when removed)
+type Change struct {
+	Type ChangeType  `json:"type" yaml:"type"`
+	Path Path        `json:"path" yaml:"path"`
+	From interface{} `json:"from,omitempty" yaml:"from,omitempty"`
+	To   interface{} `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: added %#v", c.Path, c.To)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: removed %#v", c.Path, c.From)
+	default:
+		return fmt.Sprintf("%s: %s %#v => %#v", c.Path, c.Type, c.From, c.To)
+	}
+}
+
+// Diff returns the changes which turn config tree a into config tree b
+func Diff(a, b map[string]interface{}) []Change {
+	return DiffWithOptions(a, b, NewConfig())
+}
+
+// DiffWithOptions returns the changes which turn config tree a into config tree b
+//
+// hash keys are compared in sorted order so the same trees always return
+// the same changes; arrays are compared by index unless both arrays only
+// contain hashes and a key identifies them at that path (see
+// Config.MergeHashArraysByKey and Config.MergeHashArraysByKeyAtPath), in
+// which case hashes are matched by that key and their paths use it as an
+// identity segment (e.g. containers[name=web].image)
+func DiffWithOptions(a, b map[string]interface{}, o *Config) []Change {
+	return diffHashes(a, b, o, make([]Change, 0))
+}
+
+func diffValues(a, b interface{}, o *Config, changes []Change) []Change {
+	if equalValues(a, b) {
+		return changes
+	}
+
+	if diffTypeName(a) != diffTypeName(b) {
+		return append(changes, Change{Type: ChangeTypeChanged, Path: o.path, From: a, To: b})
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		return diffHashes(av, b.(map[string]interface{}), o, changes)
+	case []interface{}:
+		bv := b.([]interface{})
+		if key, found := o.hashArrayKey(); found && sliceOfAll(av, isHash) && sliceOfAll(bv, isHash) && allHaveKey(av, key) && allHaveKey(bv, key) {
+			return diffHashArraysByKey(av, bv, key, o, changes)
+		}
+		return diffArraysByIndex(av, bv, o, changes)
+	default:
+		return append(changes, Change{Type: ChangeChanged, Path: o.path, From: a, To: b})
+	}
+}
+
+func diffHashes(a, b map[string]interface{}, o *Config, changes []Change) []Change {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		av, aok := a[k]
+		bv, bok := b[k]
+		switch {
+		case !bok:
+			changes = append(changes, Change{Type: ChangeRemoved, Path: o.path.Key(k), From: av})
+		case !aok:
+			changes = append(changes, Change{Type: ChangeAdded, Path: o.path.Key(k), To: bv})
+		default:
+			changes = diffValues(av, bv, o.copyForKey(k), changes)
+		}
+	}
+	return changes
+}
+
+func diffArraysByIndex(a, b []interface{}, o *Config, changes []Change) []Change {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(b):
+			changes = append(changes, Change{Type: ChangeRemoved, Path: o.path.Index(i), From: a[i]})
+		case i >= len(a):
+			changes = append(changes, Change{Type: ChangeAdded, Path: o.path.Index(i), To: b[i]})
+		default:
+			changes = diffValues(a[i], b[i], o.copyForIndex(i), changes)
+		}
+	}
+	return changes
+}
+
+// diffHashArraysByKey matches the hashes in a and b by the value of key,
+// reporting removed hashes in the order of a then changed and added
+// hashes in the order of b
+func diffHashArraysByKey(a, b []interface{}, key string, o *Config, changes []Change) []Change {
+	bByID := make(map[string]interface{})
+	for _, bv := range b {
+		bByID[fmt.Sprintf("%v", bv.(map[string]interface{})[key])] = bv
+	}
+	aByID := make(map[string]interface{})
+	for _, av := range a {
+		id := fmt.Sprintf("%v", av.(map[string]interface{})[key])
+		aByID[id] = av
+		if _, ok := bByID[id]; !ok {
+			changes = append(changes, Change{Type: ChangeRemoved, Path: o.path.identity(key, id), From: av})
+		}
+	}
+	for _, bv := range b {
+		id := fmt.Sprintf("%v", bv.(map[string]interface{})[key])
+		if av, ok := aByID[id]; ok {
+			var oo = *o
+			oo.path = o.path.identity(key, id)
+			changes = diffValues(av, bv, &oo, changes)
+		} else {
+			changes = append(changes, Change{Type: ChangeAdded, Path: o.path.identity(key, id), To: bv})
+		}
+	}
+	return changes
+}
+
+func allHaveKey(l []interface{}, key string) bool {
+	for _, v := range l {
+		if _, ok := v.(map[string]interface{})[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// diffTypeName is typeName with every numeric type reported as a "number"
+func diffTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return "number"
+	default:
+		return typeName(v)
+	}
+}
+
+// equalValues compares config values, treating numbers of different Go
+// types (e.g. an int parsed from YAML and a float64 parsed from JSON) as equal
+func equalValues(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if w, ok := bv[k]; !ok || !equalValues(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equalValues(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if an, ok := toFloat(a); ok {
+		bn, ok := toFloat(b)
+		return ok && an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
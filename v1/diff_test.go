@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		opt  *Config
+		want []Change
+	}{
+		{
+			name: "identical trees",
+			a:    `{"a" => {"b" => [1, 2]}, "c" => "d"}`,
+			b:    `{"a" => {"b" => [1, 2]}, "c" => "d"}`,
+			opt:  NewConfig(),
+			want: []Change{},
+		},
+		{
+			name: "added, removed and changed keys in sorted order",
+			a:    `{"log_level" => "WARN", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}}`,
+			b:    `{"log_level" => "DEBUG", "auth" => {"username" => "default_user", "password" => "dev_pass"}, "db" => {"name" => "dev_db"}}`,
+			opt:  NewConfig(),
+			want: []Change{
+				{Type: ChangeChanged, Path: Path{"auth", "password"}, From: "default_pass", To: "dev_pass"},
+				{Type: ChangeAdded, Path: Path{"db"}, To: map[string]interface{}{"name": "dev_db"}},
+				{Type: ChangeRemoved, Path: Path{"env"}, From: "REQUIRED"},
+				{Type: ChangeChanged, Path: Path{"log_level"}, From: "WARN", To: "DEBUG"},
+			},
+		},
+		{
+			name: "type changes",
+			a:    `{"auth" => {"username" => "default_user"}, "hosts" => ["a"], "port" => "80", "timeout" => nil}`,
+			b:    `{"auth" => "disabled", "hosts" => {"a" => 1}, "port" => 80, "timeout" => 30}`,
+			opt:  NewConfig(),
+			want: []Change{
+				{Type: ChangeTypeChanged, Path: Path{"auth"}, From: map[string]interface{}{"username": "default_user"}, To: "disabled"},
+				{Type: ChangeTypeChanged, Path: Path{"hosts"}, From: []interface{}{"a"}, To: map[string]interface{}{"a": 1.0}},
+				{Type: ChangeTypeChanged, Path: Path{"port"}, From: "80", To: 80.0},
+				{Type: ChangeTypeChanged, Path: Path{"timeout"}, From: nil, To: 30.0},
+			},
+		},
+		{
+			name: "arrays by index",
+			a:    `{"hosts" => ["a", "b", "c"], "ports" => [80]}`,
+			b:    `{"hosts" => ["a", "x"], "ports" => [80, 443]}`,
+			opt:  NewConfig(),
+			want: []Change{
+				{Type: ChangeChanged, Path: Path{"hosts", "[1]"}, From: "b", To: "x"},
+				{Type: ChangeRemoved, Path: Path{"hosts", "[2]"}, From: "c"},
+				{Type: ChangeAdded, Path: Path{"ports", "[1]"}, To: 443.0},
+			},
+		},
+		{
+			name: "hash arrays by identity key",
+			a:    `{"containers" => [{"name" => "web", "image" => "web:1"}, {"name" => "worker", "image" => "worker:1"}]}`,
+			b:    `{"containers" => [{"name" => "sidecar", "image" => "proxy:1"}, {"name" => "web", "image" => "web:2"}]}`,
+			opt:  NewConfig().WithMergeHashArraysByKeyAtPath("containers", "name"),
+			want: []Change{
+				{Type: ChangeRemoved, Path: Path{"containers", "[name=worker]"}, From: map[string]interface{}{"name": "worker", "image": "worker:1"}},
+				{Type: ChangeAdded, Path: Path{"containers", "[name=sidecar]"}, To: map[string]interface{}{"name": "sidecar", "image": "proxy:1"}},
+				{Type: ChangeChanged, Path: Path{"containers", "[name=web]", "image"}, From: "web:1", To: "web:2"},
+			},
+		},
+		{
+			name: "hash arrays without the identity key are compared by index",
+			a:    `{"containers" => [{"name" => "web"}]}`,
+			b:    `{"containers" => [{"image" => "web:2"}]}`,
+			opt:  NewConfig().WithMergeHashArraysByKey("name"),
+			want: []Change{
+				{Type: ChangeAdded, Path: Path{"containers", "[0]", "image"}, To: "web:2"},
+				{Type: ChangeRemoved, Path: Path{"containers", "[0]", "name"}, From: "web"},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			a, err := rubyHashToMap(tt.a)
+			assert.NoError(t, err, "unmarshall a >>%s<< to map: %v", tt.a, err)
+			b, err := rubyHashToMap(tt.b)
+			assert.NoError(t, err, "unmarshall b >>%s<< to map: %v", tt.b, err)
+
+			got := DiffWithOptions(a, b, tt.opt)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestDiffNumbers checks that numbers decoded as different Go types (e.g.
+// an int from YAML and a float64 from JSON) are compared by value
+func TestDiffNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		a    map[string]interface{}
+		b    map[string]interface{}
+		want []Change
+	}{
+		{
+			name: "int and float",
+			a:    map[string]interface{}{"port": 80, "hosts": []interface{}{int64(1)}},
+			b:    map[string]interface{}{"port": 80.0, "hosts": []interface{}{float32(1)}},
+			want: []Change{},
+		},
+		{
+			name: "unsigned and float",
+			a:    map[string]interface{}{"retries": uint8(3)},
+			b:    map[string]interface{}{"retries": 4.0},
+			want: []Change{
+				{Type: ChangeChanged, Path: Path{"retries"}, From: uint8(3), To: 4.0},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			got := Diff(tt.a, tt.b)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	assert.Equal(t, `containers[name=web].image: changed "web:1" => "web:2"`, Change{Type: ChangeChanged, Path: ParsePath("containers[name=web].image"), From: "web:1", To: "web:2"}.String())
+	assert.Equal(t, `db: added "x"`, Change{Type: ChangeAdded, Path: Path{"db"}, To: "x"}.String())
+	assert.Equal(t, `env: removed "REQUIRED"`, Change{Type: ChangeRemoved, Path: Path{"env"}, From: "REQUIRED"}.String())
+}
+
+func TestChangeMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Change{Type: ChangeChanged, Path: ParsePath("containers[name=web].image"), From: "web:1", To: "web:2"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "changed", "path": "containers[name=web].image", "from": "web:1", "to": "web:2"}`, string(b))
+}
@@ -0,0 +1,196 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resolution is a policy for resolving the conflicts found by Merge3WithOptions
+type Resolution string
+
+const (
+	// ResolveFail returns every conflict as a Merge3ConflictsError (the default)
+	ResolveFail Resolution = ""
+	// ResolveOurs keeps our value for each conflict
+	ResolveOurs Resolution = "ours"
+	// ResolveTheirs keeps their value for each conflict
+	ResolveTheirs Resolution = "theirs"
+	// ResolveMarkers replaces each conflict with a hash of conflict markers (see ConflictMarkers)
+	ResolveMarkers Resolution = "markers"
+)
+
+const (
+	conflictMarkerOurs   = "<<<<<<< ours"
+	conflictMarkerBase   = "||||||| base"
+	conflictMarkerTheirs = ">>>>>>> theirs"
+)
+
+// ParseResolution returns the Resolution named by s
+func ParseResolution(s string) (Resolution, error) {
+	switch r := Resolution(s); r {
+	case ResolveOurs, ResolveTheirs, ResolveMarkers:
+		return r, nil
+	case "fail":
+		return ResolveFail, nil
+	default:
+		return ResolveFail, fmt.Errorf("unknown conflict resolution %#v: supported resolutions are fail, ours, theirs, markers", s)
+	}
+}
+
+// Merge3Conflict describes a path which ours and theirs both changed
+// differently relative to base; a value which is missing on one side
+// (never added or deleted) is nil with the matching Missing flag set
+type Merge3Conflict struct {
+	Path          Path        `json:"path" yaml:"path"`
+	Base          interface{} `json:"base,omitempty" yaml:"base,omitempty"`
+	Ours          interface{} `json:"ours,omitempty" yaml:"ours,omitempty"`
+	Theirs        interface{} `json:"theirs,omitempty" yaml:"theirs,omitempty"`
+	BaseMissing   bool        `json:"base_missing,omitempty" yaml:"base_missing,omitempty"`
+	OursMissing   bool        `json:"ours_missing,omitempty" yaml:"ours_missing,omitempty"`
+	TheirsMissing bool        `json:"theirs_missing,omitempty" yaml:"theirs_missing,omitempty"`
+}
+
+func (c *Merge3Conflict) Error() string {
+	path := c.Path.String()
+	if path == "" {
+		path = "<root>"
+	}
+	return fmt.Sprintf("%s: ours %s but theirs %s", path, describeSide(c.Ours, c.OursMissing, c.BaseMissing), describeSide(c.Theirs, c.TheirsMissing, c.BaseMissing))
+}
+
+func describeSide(v interface{}, missing, baseMissing bool) string {
+	switch {
+	case missing:
+		return "deleted it"
+	case baseMissing:
+		return fmt.Sprintf("added %#v", v)
+	default:
+		return fmt.Sprintf("changed it to %#v", v)
+	}
+}
+
+// Merge3ConflictsError collects every Merge3Conflict found by a three-way merge
+type Merge3ConflictsError []*Merge3Conflict
+
+func (e Merge3ConflictsError) Error() string {
+	lines := make([]string, len(e))
+	for i, c := range e {
+		lines[i] = "- " + c.Error()
+	}
+	return fmt.Sprintf("found %d three-way merge conflict(s):\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// ConflictMarkers returns the hash which ResolveMarkers writes in place of c
+// (e.g. {"<<<<<<< ours": 2, "||||||| base": 1, ">>>>>>> theirs": 3}); a
+// missing value has no marker
+func (c *Merge3Conflict) ConflictMarkers() map[string]interface{} {
+	m := make(map[string]interface{})
+	if !c.OursMissing {
+		m[conflictMarkerOurs] = c.Ours
+	}
+	if !c.BaseMissing {
+		m[conflictMarkerBase] = c.Base
+	}
+	if !c.TheirsMissing {
+		m[conflictMarkerTheirs] = c.Theirs
+	}
+	return m
+}
+
+// Merge3 applies the changes both ours and theirs made relative to base and
+// returns the result, or a Merge3ConflictsError when they made different
+// changes to the same path
+func Merge3(base, ours, theirs map[string]interface{}) (map[string]interface{}, error) {
+	r, _, err := Merge3WithOptions(base, ours, theirs, NewConfig())
+	return r, err
+}
+
+// Merge3WithOptions applies the changes both ours and theirs made relative
+// to base and returns the result along with every conflict found, sorted
+// by path; conflicts are resolved by o.ConflictResolution, and with
+// ResolveFail (the default) they are also returned as the error
+//
+// hashes are merged key by key; arrays and scalars are compared as a
+// whole so a value changed by only one side takes that change and a value
+// changed differently by both sides is a conflict; neither base, ours
+// nor theirs are modified
+func Merge3WithOptions(base, ours, theirs map[string]interface{}, o *Config) (map[string]interface{}, Merge3ConflictsError, error) {
+	conflicts := make(Merge3ConflictsError, 0)
+	result := merge3Hashes(base, ours, theirs, o, &conflicts)
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Path.String() < conflicts[j].Path.String() })
+
+	if len(conflicts) > 0 && o.ConflictResolution == ResolveFail {
+		return nil, conflicts, conflicts
+	}
+	return result, conflicts, nil
+}
+
+func merge3Hashes(base, ours, theirs map[string]interface{}, o *Config, conflicts *Merge3ConflictsError) map[string]interface{} {
+	keys := make(map[string]bool)
+	for _, m := range []map[string]interface{}{base, ours, theirs} {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+
+	result := make(map[string]interface{})
+	for k := range keys {
+		b, hasBase := base[k]
+		v, hasOurs := ours[k]
+		t, hasTheirs := theirs[k]
+		if r, ok := merge3Values(b, v, t, hasBase, hasOurs, hasTheirs, o.copyForKey(k), conflicts); ok {
+			result[k] = r
+		}
+	}
+	return result
+}
+
+// merge3Values returns the merged value and true, or false when the merged value is deleted
+func merge3Values(base, ours, theirs interface{}, hasBase, hasOurs, hasTheirs bool, o *Config, conflicts *Merge3ConflictsError) (interface{}, bool) {
+	switch {
+	case hasOurs == hasTheirs && equalValues(ours, theirs):
+		// both sides agree (or neither changed it)
+		return DeepCopy(ours), hasOurs
+	case hasOurs == hasBase && equalValues(ours, base):
+		o.writeDebug("%s: taking theirs %#v", o.path, theirs)
+		return DeepCopy(theirs), hasTheirs
+	case hasTheirs == hasBase && equalValues(theirs, base):
+		o.writeDebug("%s: taking ours %#v", o.path, ours)
+		return DeepCopy(ours), hasOurs
+	}
+
+	oursHash, oursIsHash := ours.(map[string]interface{})
+	theirsHash, theirsIsHash := theirs.(map[string]interface{})
+	if oursIsHash && theirsIsHash {
+		// both sides changed a hash (or added one) so merge their changes key by key
+		baseHash, ok := base.(map[string]interface{})
+		if !ok {
+			baseHash = make(map[string]interface{})
+		}
+		return merge3Hashes(baseHash, oursHash, theirsHash, o, conflicts), true
+	}
+
+	c := &Merge3Conflict{
+		Path:          o.path,
+		Base:          base,
+		Ours:          ours,
+		Theirs:        theirs,
+		BaseMissing:   !hasBase,
+		OursMissing:   !hasOurs,
+		TheirsMissing: !hasTheirs,
+	}
+	o.writeDebug("conflict: %v", c)
+	*conflicts = append(*conflicts, c)
+
+	switch o.ConflictResolution {
+	case ResolveOurs:
+		return DeepCopy(ours), hasOurs
+	case ResolveTheirs:
+		return DeepCopy(theirs), hasTheirs
+	case ResolveMarkers:
+		return DeepCopy(c.ConflictMarkers()), true
+	default:
+		return nil, false
+	}
+}
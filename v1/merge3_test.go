@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	base := `{"log_level" => "WARN", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"], "region" => "unknown"}`
+
+	tests := []struct {
+		name          string
+		ours          string
+		theirs        string
+		opt           *Config
+		want          string
+		wantErr       bool
+		wantConflicts []string
+	}{
+		{
+			name:   "changes to different paths are combined",
+			ours:   `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"], "region" => "unknown"}`,
+			theirs: `{"log_level" => "WARN", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "new_pass"}, "hosts" => ["a", "b"], "db" => {"name" => "db"}}`,
+			opt:    NewConfig(),
+			want:   `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "new_pass"}, "hosts" => ["a", "b"], "db" => {"name" => "db"}}`,
+		},
+		{
+			name:   "identical changes are not conflicts",
+			ours:   `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user"}, "hosts" => ["a"], "region" => "unknown", "db" => {"name" => "db"}}`,
+			theirs: `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user"}, "hosts" => ["a"], "db" => {"name" => "db"}}`,
+			opt:    NewConfig(),
+			want:   `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user"}, "hosts" => ["a"], "db" => {"name" => "db"}}`,
+		},
+		{
+			name:          "different changes to the same path are conflicts",
+			ours:          `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "ours"}, "hosts" => ["a", "b"], "region" => "us-east-1", "db" => {"name" => "ours"}}`,
+			theirs:        `{"log_level" => "INFO", "env" => "REQUIRED", "auth" => {"username" => "default_user"}, "hosts" => ["a", "c"], "region" => "unknown", "db" => {"name" => "theirs", "port" => 5432}}`,
+			opt:           NewConfig(),
+			wantErr:       true,
+			wantConflicts: []string{"auth.password", "db.name", "hosts", "log_level"},
+		},
+		{
+			name:          "changing a hash into a scalar on one side conflicts with changes inside it",
+			ours:          `{"log_level" => "WARN", "env" => "REQUIRED", "auth" => "disabled", "hosts" => ["a"], "region" => "unknown"}`,
+			theirs:        `{"log_level" => "WARN", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "new_pass"}, "hosts" => ["a"], "region" => "unknown"}`,
+			opt:           NewConfig(),
+			wantErr:       true,
+			wantConflicts: []string{"auth"},
+		},
+		{
+			name:          "resolve conflicts with ours",
+			ours:          `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"]}`,
+			theirs:        `{"log_level" => "INFO", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"], "region" => "us-east-1"}`,
+			opt:           NewConfig().WithConflictResolution(ResolveOurs),
+			wantConflicts: []string{"log_level", "region"},
+			want:          `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"]}`,
+		},
+		{
+			name:          "resolve conflicts with theirs",
+			ours:          `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"]}`,
+			theirs:        `{"log_level" => "INFO", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"], "region" => "us-east-1"}`,
+			opt:           NewConfig().WithConflictResolution(ResolveTheirs),
+			wantConflicts: []string{"log_level", "region"},
+			want:          `{"log_level" => "INFO", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"], "region" => "us-east-1"}`,
+		},
+		{
+			name:          "resolve conflicts with markers",
+			ours:          `{"log_level" => "DEBUG", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"]}`,
+			theirs:        `{"log_level" => "INFO", "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"], "region" => "us-east-1"}`,
+			opt:           NewConfig().WithConflictResolution(ResolveMarkers),
+			wantConflicts: []string{"log_level", "region"},
+			want:          `{"log_level" => {"<<<<<<< ours" => "DEBUG", "||||||| base" => "WARN", ">>>>>>> theirs" => "INFO"}, "env" => "REQUIRED", "auth" => {"username" => "default_user", "password" => "default_pass"}, "hosts" => ["a"], "region" => {"||||||| base" => "unknown", ">>>>>>> theirs" => "us-east-1"}}`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			b, err := rubyHashToMap(base)
+			assert.NoError(t, err, "unmarshall base >>%s<< to map: %v", base, err)
+			o, err := rubyHashToMap(tt.ours)
+			assert.NoError(t, err, "unmarshall ours >>%s<< to map: %v", tt.ours, err)
+			th, err := rubyHashToMap(tt.theirs)
+			assert.NoError(t, err, "unmarshall theirs >>%s<< to map: %v", tt.theirs, err)
+
+			// Act
+			got, conflicts, err := Merge3WithOptions(b, o, th, tt.opt)
+
+			// Assert
+			paths := make([]string, 0)
+			for _, c := range conflicts {
+				paths = append(paths, c.Path.String())
+			}
+			if tt.wantConflicts != nil {
+				assert.Equal(t, tt.wantConflicts, paths)
+			} else {
+				assert.Empty(t, paths)
+			}
+
+			if tt.wantErr {
+				var errConflicts Merge3ConflictsError
+				if assert.True(t, errors.As(err, &errConflicts), "Merge3WithOptions() error = %v, want Merge3ConflictsError", err) {
+					assert.Equal(t, conflicts, errConflicts)
+				}
+				return
+			}
+
+			assert.NoError(t, err, "Merge3WithOptions()")
+			w, err := rubyHashToMap(tt.want)
+			assert.NoError(t, err, "unmarshall expectation >>%s<< to map: %v", tt.want, err)
+			assert.Equal(t, w, got, "Merge3WithOptions() got = %v, want %v", got, w)
+		})
+	}
+}
+
+func TestMerge3ConflictError(t *testing.T) {
+	_, err := Merge3(
+		map[string]interface{}{"a": "1", "b": "1"},
+		map[string]interface{}{"a": "2", "c": "ours"},
+		map[string]interface{}{"a": "3", "b": "2", "c": "theirs"},
+	)
+	assert.EqualError(t, err, `found 3 three-way merge conflict(s):
+- a: ours changed it to "2" but theirs changed it to "3"
+- b: ours deleted it but theirs changed it to "2"
+- c: ours added "ours" but theirs added "theirs"`)
+}
+
+// TestMerge3Numbers checks that numbers decoded as different Go types (e.g.
+// an int from YAML and a float64 from JSON) are compared by value
+func TestMerge3Numbers(t *testing.T) {
+	got, err := Merge3(
+		map[string]interface{}{"port": 80, "hosts": []interface{}{1}},
+		map[string]interface{}{"port": 80.0, "hosts": []interface{}{int64(2)}},
+		map[string]interface{}{"port": 81, "hosts": []interface{}{uint8(1)}},
+	)
+	assert.NoError(t, err, "Merge3()")
+	assert.Equal(t, map[string]interface{}{"port": 81, "hosts": []interface{}{int64(2)}}, got)
+}
+
+func TestParseResolution(t *testing.T) {
+	for _, s := range []string{"fail", "ours", "theirs", "markers"} {
+		_, err := ParseResolution(s)
+		assert.NoError(t, err, s)
+	}
+	_, err := ParseResolution("union")
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package v1
+
+import "reflect"
+
+// Merger is a custom merge function consulted by deepMerge before the
+// built-in hash, array and scalar merge logic
+//
+// return handled=false to fall back to the built-in merge for src and
+// dest; otherwise the returned value replaces dest at path
+type Merger interface {
+	Merge(path Path, src, dest interface{}, cfg *Config) (result interface{}, handled bool, err error)
+}
+
+// MergerFunc adapts an ordinary function to the Merger interface
+type MergerFunc func(path Path, src, dest interface{}, cfg *Config) (interface{}, bool, error)
+
+// Merge calls f(path, src, dest, cfg)
+func (f MergerFunc) Merge(path Path, src, dest interface{}, cfg *Config) (interface{}, bool, error) {
+	return f(path, src, dest, cfg)
+}
+
+// mergerRegistration scopes a Merger to values at paths matching a
+// pattern and/or to src or dest values of a given Go type
+type mergerRegistration struct {
+	pattern *string
+	typ     reflect.Type
+	merger  Merger
+}
+
+func (r mergerRegistration) applies(path Path, src, dest interface{}) bool {
+	if r.pattern != nil && !path.Match(*r.pattern) {
+		return false
+	}
+	if r.typ != nil && reflect.TypeOf(src) != r.typ && reflect.TypeOf(dest) != r.typ {
+		return false
+	}
+	return true
+}
+
+// customMerge offers src and dest to each registered Merger in turn until one handles them
+func (c *Config) customMerge(src, dest interface{}) (interface{}, bool, error) {
+	for _, r := range c.mergers {
+		if !r.applies(c.path, src, dest) {
+			continue
+		}
+		result, handled, err := r.merger.Merge(c.path, src, dest, c)
+		if err != nil {
+			return nil, true, err
+		}
+		if handled {
+			c.writeDebug("custom merger handled %s: %#v :: %#v => %#v", c.path, src, dest, result)
+			return result, true, nil
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// semverMax is an example Merger which keeps the highest of two version pins
+var semverMax = MergerFunc(func(path Path, src, dest interface{}, cfg *Config) (interface{}, bool, error) {
+	s, sok := src.(string)
+	d, dok := dest.(string)
+	if !sok || !dok {
+		return nil, false, nil
+	}
+	sv, err := parseVersion(s)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %v", path, err)
+	}
+	dv, err := parseVersion(d)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range sv {
+		if sv[i] != dv[i] {
+			if sv[i] > dv[i] {
+				return s, true, nil
+			}
+			return d, true, nil
+		}
+	}
+	return d, true, nil
+})
+
+func parseVersion(s string) ([3]int, error) {
+	var v [3]int
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("%#v is not a semantic version", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, fmt.Errorf("%#v is not a semantic version", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// cidrList is an example custom type merged with a type-keyed Merger
+type cidrList []string
+
+var cidrUnion = MergerFunc(func(path Path, src, dest interface{}, cfg *Config) (interface{}, bool, error) {
+	seen := make(map[string]bool)
+	result := make(cidrList, 0)
+	for _, v := range []interface{}{dest, src} {
+		if l, ok := v.(cidrList); ok {
+			for _, c := range l {
+				if !seen[c] {
+					seen[c] = true
+					result = append(result, c)
+				}
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, true, nil
+})
+
+// appendVersion is an example Merger which is not idempotent: it appends src to dest, or to 0 when there is no dest
+var appendVersion = MergerFunc(func(path Path, src, dest interface{}, cfg *Config) (interface{}, bool, error) {
+	if dest == nil {
+		dest = "0"
+	}
+	return fmt.Sprintf("%v+%v", dest, src), true, nil
+})
+
+func TestCustomMergers(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     map[string]interface{}
+		dest    map[string]interface{}
+		opt     *Config
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "merger for a path keeps the highest version",
+			src:  map[string]interface{}{"image": map[string]interface{}{"version": "v1.2.3", "name": "api"}, "name": "v1.0.0"},
+			dest: map[string]interface{}{"image": map[string]interface{}{"version": "v1.10.0", "name": "web"}, "name": "v2.0.0"},
+			opt:  NewConfigDeeperMergeBang().WithMergerForPath("**.version", semverMax),
+			want: map[string]interface{}{"image": map[string]interface{}{"version": "v1.10.0", "name": "api"}, "name": "v1.0.0"},
+		},
+		{
+			name:    "merger errors stop the merge",
+			src:     map[string]interface{}{"version": "latest"},
+			dest:    map[string]interface{}{"version": "v1.0.0"},
+			opt:     NewConfigDeeperMergeBang().WithMergerForPath("version", semverMax),
+			wantErr: true,
+		},
+		{
+			name: "unhandled values fall back to the built-in merge",
+			src:  map[string]interface{}{"version": []interface{}{"v1.0.0"}},
+			dest: map[string]interface{}{"version": []interface{}{"v2.0.0"}},
+			opt:  NewConfigDeeperMergeBang().WithMergerForPath("version", semverMax),
+			want: map[string]interface{}{"version": []interface{}{"v2.0.0", "v1.0.0"}},
+		},
+		{
+			name: "merger is offered a nil dest for a new key",
+			src:  map[string]interface{}{"version": "1.2"},
+			dest: map[string]interface{}{},
+			opt:  NewConfigDeeperMergeBang().WithMergerForPath("version", appendVersion),
+			want: map[string]interface{}{"version": "0+1.2"},
+		},
+		{
+			name: "merger is offered a nil dest",
+			src:  map[string]interface{}{"version": "1.2"},
+			dest: map[string]interface{}{"version": nil},
+			opt:  NewConfigDeeperMergeBang().WithMergerForPath("version", appendVersion),
+			want: map[string]interface{}{"version": "0+1.2"},
+		},
+		{
+			name: "merger appends to an existing dest",
+			src:  map[string]interface{}{"version": "1.2"},
+			dest: map[string]interface{}{"version": "1.1"},
+			opt:  NewConfigDeeperMergeBang().WithMergerForPath("version", appendVersion),
+			want: map[string]interface{}{"version": "1.1+1.2"},
+		},
+		{
+			name: "merger for a type",
+			src:  map[string]interface{}{"allow": cidrList{"10.0.0.0/8", "192.168.0.0/16"}, "deny": "0.0.0.0/0"},
+			dest: map[string]interface{}{"allow": cidrList{"172.16.0.0/12", "10.0.0.0/8"}, "deny": "none"},
+			opt:  NewConfigDeeperMergeBang().WithMergerForType(reflect.TypeOf(cidrList{}), cidrUnion),
+			want: map[string]interface{}{"allow": cidrList{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}, "deny": "0.0.0.0/0"},
+		},
+		{
+			name: "first merger to handle a value wins",
+			src:  map[string]interface{}{"a": "src", "b": "src"},
+			dest: map[string]interface{}{"a": "dest", "b": "dest"},
+			opt: NewConfigDeeperMergeBang().
+				WithMergerForPath("a", MergerFunc(func(path Path, src, dest interface{}, cfg *Config) (interface{}, bool, error) {
+					return "first", true, nil
+				})).
+				WithMerger(MergerFunc(func(path Path, src, dest interface{}, cfg *Config) (interface{}, bool, error) {
+					if len(path) == 0 {
+						return nil, false, nil
+					}
+					return "second", true, nil
+				})),
+			want: map[string]interface{}{"a": "first", "b": "second"},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			got, err := MergeWithOptions(tt.src, tt.dest, tt.opt)
+			if (err != nil) != tt.wantErr {
+				assert.FailNow(t, "MergeWithOptions()", "error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				assert.Equal(t, tt.want, got, "MergeWithOptions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,518 @@
+package v1
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"sort"
+)
+
+// MergeNodes deep merges the src YAML document into the dest YAML document with the default options and returns a new document
+func MergeNodes(src, dest *yaml.Node) (*yaml.Node, error) {
+	return MergeNodesWithOptions(src, dest, NewConfig())
+}
+
+// MergeNodesWithOptions deep merges the src YAML document into the dest YAML document with the given options and returns a new document
+//
+// values are merged exactly as MergeWithOptions merges them; the result
+// is then laid out like dest so that dest comments, key order, quoting
+// and flow styles, anchors and aliases are kept where the merged value
+// is unchanged, new keys follow in src order and values written by src
+// keep the comments and style from src
+//
+// src and dest may be document or mapping nodes (e.g. from yaml.Unmarshal
+// into a yaml.Node) and neither is modified
+func MergeNodesWithOptions(src, dest *yaml.Node, o *Config) (*yaml.Node, error) {
+	s, err := decodeMapping(src, o)
+	if err != nil {
+		return nil, fmt.Errorf("decoding src: %v", err)
+	}
+	d, err := decodeMapping(dest, o)
+	if err != nil {
+		return nil, fmt.Errorf("decoding dest: %v", err)
+	}
+
+	r, err := MergeWithOptions(s, d, o)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &nodeBuilder{anchors: make(map[string]interface{}), o: o}
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	for _, n := range []*yaml.Node{src, dest} {
+		if n != nil && n.Kind == yaml.DocumentNode && (n.HeadComment != "" || n.FootComment != "") {
+			doc.HeadComment, doc.FootComment = n.HeadComment, n.FootComment
+		}
+	}
+	content, err := b.build(r, []*yaml.Node{mappingNode(dest), mappingNode(src)})
+	if err != nil {
+		return nil, err
+	}
+	doc.Content = []*yaml.Node{content}
+	return doc, nil
+}
+
+// mappingNode returns the mapping node at the root of n, or nil when n is empty
+func mappingNode(n *yaml.Node) *yaml.Node {
+	if n != nil && n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+	if n == nil || n.Kind == 0 {
+		return nil
+	}
+	return n
+}
+
+func decodeMapping(n *yaml.Node, o *Config) (map[string]interface{}, error) {
+	if mappingNode(n) == nil {
+		return make(map[string]interface{}), nil
+	}
+	v, err := DecodeNode(n, o)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a hash but found %s", typeName(v))
+	}
+	return m, nil
+}
+
+// DecodeNode decodes the YAML node n into hashes, arrays and scalars
+//
+// by default merge keys (<<) copy the top-level keys of the merged
+// mappings as yaml.v3 does; with o.DeepMergeKeys the merged mappings are
+// deep merged with o instead so that a mapping can extend a hash it
+// inherits from an anchor rather than replace it
+func DecodeNode(n *yaml.Node, o *Config) (interface{}, error) {
+	if !o.DeepMergeKeys {
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return DecodeNode(n.Content[0], o)
+	case yaml.AliasNode:
+		return DecodeNode(n.Alias, o)
+	case yaml.SequenceNode:
+		l := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			v, err := DecodeNode(item, o)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{})
+		sources := make([]map[string]interface{}, 0)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if isMergeKey(n.Content[i]) {
+				provided, err := decodeMergeSources(n.Content[i+1], o)
+				if err != nil {
+					return nil, err
+				}
+				sources = append(sources, provided...)
+				continue
+			}
+			v, err := DecodeNode(n.Content[i+1], o)
+			if err != nil {
+				return nil, err
+			}
+			m[n.Content[i].Value] = v
+		}
+		if len(sources) == 0 {
+			return m, nil
+		}
+		merged, err := combineMergeSources(sources, o)
+		if err != nil {
+			return nil, err
+		}
+		return MergeCopyWithOptions(m, merged, o.copyWithoutTracking())
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// decodeMergeSources decodes the value of a merge key: a mapping or a sequence of mappings
+func decodeMergeSources(n *yaml.Node, o *Config) ([]map[string]interface{}, error) {
+	items := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		items = n.Content
+	}
+	sources := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		v, err := DecodeNode(item, o)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("line %d: merge keys (<<) must merge a hash or an array of hashes, not %s", item.Line, typeName(v))
+		}
+		sources = append(sources, m)
+	}
+	return sources, nil
+}
+
+// combineMergeSources combines the mappings merged into a mapping where earlier mappings win
+func combineMergeSources(sources []map[string]interface{}, o *Config) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for i := len(sources) - 1; i >= 0; i-- {
+		if !o.DeepMergeKeys {
+			for k, v := range sources[i] {
+				result[k] = v
+			}
+			continue
+		}
+		r, err := MergeCopyWithOptions(sources[i], result, o.copyWithoutTracking())
+		if err != nil {
+			return nil, err
+		}
+		result = r
+	}
+	return result, nil
+}
+
+// nodeBuilder lays out a merged value as a yaml.Node reusing the nodes it was merged from
+type nodeBuilder struct {
+	// anchors maps each anchor already written to the value it was written with
+	anchors map[string]interface{}
+	o       *Config
+}
+
+func (b *nodeBuilder) decode(n *yaml.Node) (interface{}, error) {
+	return DecodeNode(n, b.o)
+}
+
+// build returns a node for v, reusing the first candidate node which
+// already holds v and borrowing style, comments and key order from the
+// candidates for hashes and arrays which changed
+func (b *nodeBuilder) build(v interface{}, candidates []*yaml.Node) (*yaml.Node, error) {
+	resolved := make([]*yaml.Node, 0, len(candidates))
+	for _, c := range candidates {
+		if c == nil {
+			continue
+		}
+		if c.Kind == yaml.AliasNode {
+			if written, ok := b.anchors[c.Value]; ok && reflect.DeepEqual(written, v) {
+				alias := *c
+				return &alias, nil
+			}
+			c = c.Alias
+		}
+		resolved = append(resolved, c)
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		return b.buildMapping(vv, resolved)
+	case []interface{}:
+		return b.buildSequence(vv, resolved)
+	default:
+		for _, c := range resolved {
+			if c.Kind != yaml.ScalarNode {
+				continue
+			}
+			if cv, err := b.decode(c); err == nil && reflect.DeepEqual(cv, v) {
+				n := *c
+				b.writeAnchor(&n, v)
+				return &n, nil
+			}
+		}
+		n := &yaml.Node{}
+		if err := n.Encode(v); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+}
+
+func (b *nodeBuilder) buildMapping(v map[string]interface{}, candidates []*yaml.Node) (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	mappings := make([]*yaml.Node, 0)
+	for _, c := range candidates {
+		if c.Kind == yaml.MappingNode {
+			mappings = append(mappings, c)
+		}
+	}
+	if len(mappings) > 0 {
+		base := mappings[0]
+		n.Style, n.HeadComment, n.LineComment, n.FootComment = base.Style, base.HeadComment, base.LineComment, base.FootComment
+		n.Anchor = base.Anchor
+		b.writeAnchor(n, v)
+	}
+
+	written := make(map[string]bool)
+	if len(mappings) > 0 {
+		// keep merge keys (<<) of the first mapping when every key they provide is unchanged
+		base := mappings[0]
+		for i := 0; i+1 < len(base.Content); i += 2 {
+			if !isMergeKey(base.Content[i]) {
+				continue
+			}
+			sources, err := decodeMergeSources(base.Content[i+1], b.o)
+			if err != nil {
+				continue
+			}
+			provided, err := combineMergeSources(sources, b.o)
+			if err != nil {
+				continue
+			}
+			if !mergeKeyUnchanged(base, provided, v) {
+				continue
+			}
+			value, err := b.buildMergeValue(base.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			key := *base.Content[i]
+			// leave the tag of the merge key implicit so it is not written as !!merge
+			key.Tag = ""
+			n.Content = append(n.Content, &key, value)
+			for k := range provided {
+				if !hasExplicitKey(base, k) {
+					written[k] = true
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(v))
+	seen := make(map[string]bool)
+	for _, m := range mappings {
+		for _, k := range orderedKeys(m) {
+			if _, ok := v[k]; ok && !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	rest := make([]string, 0)
+	for k := range v {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	for _, k := range keys {
+		if written[k] {
+			continue
+		}
+		var keyNode *yaml.Node
+		values := make([]*yaml.Node, 0)
+		for _, m := range mappings {
+			if kn, vn := lookupKey(m, k); kn != nil {
+				if keyNode == nil {
+					keyNode = kn
+				}
+				values = append(values, vn)
+			}
+		}
+		if keyNode == nil {
+			keyNode = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}
+		} else {
+			kn := *keyNode
+			keyNode = &kn
+		}
+		value, err := b.build(v[k], values)
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, keyNode, value)
+	}
+	return n, nil
+}
+
+func (b *nodeBuilder) buildSequence(v []interface{}, candidates []*yaml.Node) (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	sequences := make([]*yaml.Node, 0)
+	for _, c := range candidates {
+		if c.Kind == yaml.SequenceNode {
+			sequences = append(sequences, c)
+		}
+	}
+	if len(sequences) > 0 {
+		base := sequences[0]
+		n.Style, n.HeadComment, n.LineComment, n.FootComment = base.Style, base.HeadComment, base.LineComment, base.FootComment
+		n.Anchor = base.Anchor
+		b.writeAnchor(n, v)
+	}
+
+	used := make(map[*yaml.Node]bool)
+	for i, item := range v {
+		// prefer an unused item holding the same value, falling back to the items at the same index
+		var match *yaml.Node
+		for _, s := range sequences {
+			for _, c := range s.Content {
+				if used[c] {
+					continue
+				}
+				if cv, err := b.decode(c); err == nil && reflect.DeepEqual(cv, item) {
+					match = c
+					break
+				}
+			}
+			if match != nil {
+				break
+			}
+		}
+
+		itemCandidates := make([]*yaml.Node, 0)
+		if match != nil {
+			used[match] = true
+			itemCandidates = append(itemCandidates, match)
+		} else {
+			for _, s := range sequences {
+				if i < len(s.Content) {
+					itemCandidates = append(itemCandidates, s.Content[i])
+				}
+			}
+		}
+		value, err := b.build(item, itemCandidates)
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, value)
+	}
+	return n, nil
+}
+
+// buildMergeValue returns a node for the value of a merge key (an alias,
+// a mapping or a sequence of them) which is written unchanged
+func (b *nodeBuilder) buildMergeValue(n *yaml.Node) (*yaml.Node, error) {
+	if n.Kind != yaml.SequenceNode {
+		v, err := b.decode(n)
+		if err != nil {
+			return nil, err
+		}
+		return b.build(v, []*yaml.Node{n})
+	}
+
+	seq := *n
+	seq.Content = make([]*yaml.Node, 0, len(n.Content))
+	for _, item := range n.Content {
+		value, err := b.buildMergeValue(item)
+		if err != nil {
+			return nil, err
+		}
+		seq.Content = append(seq.Content, value)
+	}
+	return &seq, nil
+}
+
+// writeAnchor notes that n is written with the value v, dropping its anchor when it was already written
+func (b *nodeBuilder) writeAnchor(n *yaml.Node, v interface{}) {
+	if n.Anchor == "" {
+		return
+	}
+	if _, ok := b.anchors[n.Anchor]; ok {
+		n.Anchor = ""
+		return
+	}
+	b.anchors[n.Anchor] = v
+}
+
+// mappingKeys lists the explicit keys of mapping node m in order
+func mappingKeys(m *yaml.Node) []string {
+	keys := make([]string, 0, len(m.Content)/2)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if !isMergeKey(m.Content[i]) {
+			keys = append(keys, m.Content[i].Value)
+		}
+	}
+	return keys
+}
+
+// orderedKeys lists the explicit keys of mapping node m in order followed
+// by the keys of the mappings merged into m (<<)
+func orderedKeys(m *yaml.Node) []string {
+	keys := mappingKeys(m)
+	for _, s := range mergeSources(m) {
+		keys = append(keys, orderedKeys(s)...)
+	}
+	return keys
+}
+
+// mergeSources lists the mapping nodes merged into mapping node m (<<) in order of precedence
+func mergeSources(m *yaml.Node) []*yaml.Node {
+	sources := make([]*yaml.Node, 0)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if !isMergeKey(m.Content[i]) {
+			continue
+		}
+		items := []*yaml.Node{m.Content[i+1]}
+		if m.Content[i+1].Kind == yaml.SequenceNode {
+			items = m.Content[i+1].Content
+		}
+		for _, s := range items {
+			if s.Kind == yaml.AliasNode {
+				s = s.Alias
+			}
+			if s != nil && s.Kind == yaml.MappingNode {
+				sources = append(sources, s)
+			}
+		}
+	}
+	return sources
+}
+
+// lookupKey returns the key and value nodes for k in mapping node m,
+// looking in the mappings merged into m (<<) when k is not an explicit key
+func lookupKey(m *yaml.Node, k string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if !isMergeKey(m.Content[i]) && m.Content[i].Value == k {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	for _, s := range mergeSources(m) {
+		if kn, vn := lookupKey(s, k); kn != nil {
+			return kn, vn
+		}
+	}
+	return nil, nil
+}
+
+func hasExplicitKey(m *yaml.Node, k string) bool {
+	for _, key := range mappingKeys(m) {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func isMergeKey(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Value == "<<" && (n.Tag == "!!merge" || n.Tag == "")
+}
+
+// mergeKeyUnchanged returns true when every key which a merge key
+// provides to mapping m (and m does not override) still holds the same value in v
+func mergeKeyUnchanged(m *yaml.Node, provided, v map[string]interface{}) bool {
+	for k, pv := range provided {
+		if hasExplicitKey(m, k) {
+			continue
+		}
+		if rv, ok := v[k]; !ok || !reflect.DeepEqual(rv, pv) {
+			return false
+		}
+	}
+	return true
+}
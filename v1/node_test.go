@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestMergeNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		dest string
+		opt  *Config
+		want string
+	}{
+		{
+			name: "keeps dest comments, key order, style and anchors",
+			src: `log_level: DEBUG # noisy in dev
+db:
+  name: dev_app
+hosts: [c]
+cache:
+  retries: 5
+new_key: 'x' # added
+`,
+			dest: `# defaults for app1
+defaults: &defaults
+  timeout: 30 # seconds
+  retries: 3
+log_level: WARN # quiet by default
+env: "REQUIRED"
+hosts: [a, b]
+db:
+  <<: *defaults
+  name: app
+  # the port
+  port: 5432
+cache:
+  <<: *defaults
+`,
+			opt: NewConfigDeeperMergeBang(),
+			want: `# defaults for app1
+defaults: &defaults
+  timeout: 30 # seconds
+  retries: 3
+log_level: DEBUG # noisy in dev
+env: "REQUIRED"
+hosts: [a, b, c]
+db:
+  <<: *defaults
+  name: dev_app
+  # the port
+  port: 5432
+cache:
+  timeout: 30 # seconds
+  retries: 5
+new_key: 'x' # added
+`,
+		},
+		{
+			name: "new hashes keep src order and style",
+			src: `# from src
+b: 1
+a:
+  - z
+  - y
+`,
+			dest: ``,
+			opt:  NewConfig(),
+			want: `# from src
+b: 1
+a:
+  - z
+  - y
+`,
+		},
+		{
+			name: "deleted keys are dropped along with their comments",
+			src: `--env: ~
+port: ~
+`,
+			dest: `env: dev # the environment
+# the port
+port: 80
+region: unknown
+`,
+			opt: NewConfigMergePatch().WithDefaultKnockoutPrefix(),
+			want: `region: unknown
+`,
+		},
+		{
+			name: "aliases to a changed anchor are expanded",
+			src: `base:
+  retries: 5
+`,
+			dest: `base: &base
+  retries: 3
+copy: *base
+`,
+			opt: NewConfigDeeperMergeBang(),
+			want: `base: &base
+  retries: 5
+copy:
+  retries: 3
+`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			// Arrange
+			var src, dest yaml.Node
+			assert.NoError(t, yaml.Unmarshal([]byte(tt.src), &src))
+			assert.NoError(t, yaml.Unmarshal([]byte(tt.dest), &dest))
+			srcBefore, destBefore := encodeNode(t, &src), encodeNode(t, &dest)
+
+			// Act
+			got, err := MergeNodesWithOptions(&src, &dest, tt.opt)
+
+			// Assert
+			assert.NoError(t, err, "MergeNodesWithOptions()")
+			assert.Equal(t, tt.want, encodeNode(t, got))
+			assert.Equal(t, srcBefore, encodeNode(t, &src), "src is not modified")
+			assert.Equal(t, destBefore, encodeNode(t, &dest), "dest is not modified")
+		})
+	}
+}
+
+func encodeNode(t *testing.T, n *yaml.Node) string {
+	if n.Kind == 0 {
+		return ""
+	}
+	b := &bytes.Buffer{}
+	e := yaml.NewEncoder(b)
+	e.SetIndent(2)
+	assert.NoError(t, e.Encode(n))
+	return b.String()
+}
+
+func TestDecodeNode(t *testing.T) {
+	doc := `defaults: &defaults
+  timeout: 30
+  tls:
+    enabled: true
+    verify: true
+extra: &extra
+  tls:
+    ca: /etc/ca.pem
+db:
+  <<: [*defaults, *extra]
+  tls:
+    verify: false
+`
+	var n yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(doc), &n))
+
+	shallow, err := DecodeNode(&n, NewConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"timeout": 30,
+		"tls":     map[string]interface{}{"verify": false},
+	}, shallow.(map[string]interface{})["db"], "yaml.v3 copies top-level keys")
+
+	deep, err := DecodeNode(&n, NewConfigDeeperMergeBang().WithDeepMergeKeys(true))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"timeout": 30,
+		"tls":     map[string]interface{}{"enabled": true, "verify": false, "ca": "/etc/ca.pem"},
+	}, deep.(map[string]interface{})["db"], "merge keys are deep merged")
+
+	assert.NoError(t, yaml.Unmarshal([]byte("a: &a 1\nb:\n  <<: *a\n"), &n))
+	_, err = DecodeNode(&n, NewConfig().WithDeepMergeKeys(true))
+	assert.Error(t, err, "merge keys must merge hashes")
+}
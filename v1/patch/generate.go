@@ -0,0 +1,51 @@
+package patch
+
+import (
+	"sort"
+)
+
+// Generate returns the Patch which turns from into to (e.g. the dest map
+// of a merge into the merged result)
+//
+// hashes are compared key by key in sorted order so the same inputs always
+// generate the same patch; arrays and scalars which differ are replaced
+// wholesale
+func Generate(from, to map[string]interface{}) Patch {
+	return generate(Pointer{}, from, to, make(Patch, 0))
+}
+
+func generate(path Pointer, from, to map[string]interface{}, p Patch) Patch {
+	for _, k := range sortedKeys(from) {
+		if _, ok := to[k]; !ok {
+			p = append(p, Operation{Op: OpRemove, Path: path.Key(k).String()})
+		}
+	}
+	for _, k := range sortedKeys(to) {
+		tv := to[k]
+		fv, ok := from[k]
+		if !ok {
+			p = append(p, Operation{Op: OpAdd, Path: path.Key(k).String(), Value: tv})
+			continue
+		}
+		if equal(fv, tv) {
+			continue
+		}
+		fm, fok := fv.(map[string]interface{})
+		tm, tok := tv.(map[string]interface{})
+		if fok && tok {
+			p = generate(path.Key(k), fm, tm, p)
+			continue
+		}
+		p = append(p, Operation{Op: OpReplace, Path: path.Key(k).String(), Value: tv})
+	}
+	return p
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
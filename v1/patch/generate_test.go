@@ -0,0 +1,31 @@
+package patch
+
+import (
+	"github.com/davidalpert/go-deep-merge/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dest := unmarshal(t, `{"log_level": "WARN", "env": "REQUIRED", "auth": {"username": "default_user", "password": "default_pass"}, "hosts": ["a"], "a/b": {"~c": 1}}`)
+	src := unmarshal(t, `{"log_level": "DEBUG", "auth": {"password": "dev_pass"}, "hosts": ["b"], "db": {"name": "dev_db"}, "a/b": {"~c": 2}, "--env": null}`)
+
+	merged, err := v1.MergeCopyWithOptions(src, dest, v1.NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix())
+	assert.NoError(t, err)
+
+	p := Generate(dest, merged)
+	assert.Equal(t, Patch{
+		{Op: OpRemove, Path: "/env"},
+		{Op: OpReplace, Path: "/a~1b/~0c", Value: 2.0},
+		{Op: OpReplace, Path: "/auth/password", Value: "dev_pass"},
+		{Op: OpAdd, Path: "/db", Value: map[string]interface{}{"name": "dev_db"}},
+		{Op: OpReplace, Path: "/hosts", Value: []interface{}{"a", "b"}},
+		{Op: OpReplace, Path: "/log_level", Value: "DEBUG"},
+	}, p)
+
+	got, err := Apply(dest, p)
+	assert.NoError(t, err)
+	assert.Equal(t, merged, got, "applying the generated patch to dest yields the merged result")
+
+	assert.Equal(t, Patch{}, Generate(merged, merged), "no changes")
+}
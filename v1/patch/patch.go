@@ -0,0 +1,299 @@
+// Package patch applies and generates JSON Patch (RFC 6902) documents
+// against the map[string]interface{} values merged by the v1 package
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/davidalpert/go-deep-merge/v1"
+	"reflect"
+)
+
+// Op is the name of a JSON Patch operation
+type Op string
+
+const (
+	OpAdd     Op = "add"
+	OpRemove  Op = "remove"
+	OpReplace Op = "replace"
+	OpMove    Op = "move"
+	OpCopy    Op = "copy"
+	OpTest    Op = "test"
+)
+
+// Operation is a single JSON Patch operation; Path and From are JSON
+// Pointers (RFC 6901) and Value is used by add, replace and test
+type Operation struct {
+	Op    Op          `json:"op" yaml:"op"`
+	Path  string      `json:"path" yaml:"path"`
+	From  string      `json:"from,omitempty" yaml:"from,omitempty"`
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+
+	// valueMissing is set when a decoded operation has no value member, which is not the same as a null value
+	valueMissing bool
+}
+
+// MarshalJSON writes the value of add, replace and test operations even when it is null
+func (o Operation) MarshalJSON() ([]byte, error) {
+	type operation Operation
+	if o.hasValue() {
+		return json.Marshal(struct {
+			operation
+			Value interface{} `json:"value"`
+		}{operation(o), o.Value})
+	}
+	return json.Marshal(operation(o))
+}
+
+// UnmarshalJSON records whether the operation has a value member so that a missing value is not applied as null
+func (o *Operation) UnmarshalJSON(b []byte) error {
+	type operation Operation
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(b, &members); err != nil {
+		return err
+	}
+	var op operation
+	if err := json.Unmarshal(b, &op); err != nil {
+		return err
+	}
+	_, ok := members["value"]
+	op.valueMissing = !ok
+	*o = Operation(op)
+	return nil
+}
+
+func (o Operation) hasValue() bool {
+	return o.Op == OpAdd || o.Op == OpReplace || o.Op == OpTest
+}
+
+// Patch is an ordered list of operations
+type Patch []Operation
+
+// Decode parses a JSON Patch document
+func Decode(b []byte) (Patch, error) {
+	var p Patch
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("decoding JSON patch: %v", err)
+	}
+	return p, nil
+}
+
+// Apply applies each operation of p in order to a copy of doc and returns
+// the patched copy; when any operation fails (including a failed test)
+// the whole patch fails and doc is left untouched
+func Apply(doc map[string]interface{}, p Patch) (map[string]interface{}, error) {
+	var result interface{} = v1.DeepCopyMap(doc)
+	for i, op := range p {
+		var err error
+		if result, err = op.apply(result); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %v", i, op.Op, op.Path, err)
+		}
+	}
+
+	m, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("patched document is a %T, not a hash", result)
+	}
+	return m, nil
+}
+
+// apply applies o to doc and returns the updated doc
+func (o Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := ParsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	if o.hasValue() && o.valueMissing {
+		return nil, fmt.Errorf("missing value")
+	}
+
+	switch o.Op {
+	case OpAdd:
+		return add(doc, path, v1.DeepCopy(o.Value))
+	case OpRemove:
+		return remove(doc, path)
+	case OpReplace:
+		return replace(doc, path, v1.DeepCopy(o.Value))
+	case OpMove, OpCopy:
+		from, err := ParsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := from.get(doc)
+		if err != nil {
+			return nil, fmt.Errorf("from %s: %v", o.From, err)
+		}
+		if o.Op == OpCopy {
+			return add(doc, path, v1.DeepCopy(v))
+		}
+		if isPrefix(from, path) {
+			return nil, fmt.Errorf("cannot move %s into one of its children", o.From)
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, fmt.Errorf("from %s: %v", o.From, err)
+		}
+		return add(doc, path, v)
+	case OpTest:
+		v, err := path.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(v, o.Value) {
+			return nil, fmt.Errorf("test failed: found %#v, expected %#v", v, o.Value)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unsupported operation %#v", o.Op)
+	}
+}
+
+func add(doc interface{}, path Pointer, v interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	return path.update(doc, func(parent interface{}, token string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[token] = v
+			return c, nil
+		case []interface{}:
+			if token == "-" {
+				return append(c, v), nil
+			}
+			n, err := arrayIndex(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+			result := make([]interface{}, 0, len(c)+1)
+			result = append(result, c[:n]...)
+			result = append(result, v)
+			return append(result, c[n:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot add %#v to a %T", token, parent)
+		}
+	})
+}
+
+func remove(doc interface{}, path Pointer) (interface{}, error) {
+	return path.update(doc, func(parent interface{}, token string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("key %#v not found", token)
+			}
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			n, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]interface{}, 0, len(c)-1)
+			result = append(result, c[:n]...)
+			return append(result, c[n+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove %#v from a %T", token, parent)
+		}
+	})
+}
+
+func replace(doc interface{}, path Pointer, v interface{}) (interface{}, error) {
+	if _, err := path.get(doc); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return v, nil
+	}
+	return path.update(doc, func(parent interface{}, token string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[token] = v
+			return c, nil
+		case []interface{}:
+			n, _ := arrayIndex(token, len(c)-1)
+			c[n] = v
+			return c, nil
+		default:
+			return nil, fmt.Errorf("cannot replace %#v in a %T", token, parent)
+		}
+	})
+}
+
+// isPrefix returns true when p is a proper prefix of other
+func isPrefix(p, other Pointer) bool {
+	if len(p) >= len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equal compares JSON values, treating numbers of different Go types
+// (e.g. an int parsed from YAML and a float64 parsed from JSON) as equal
+func equal(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if w, ok := bv[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if an, ok := toFloat(a); ok {
+		bn, ok := toFloat(b)
+		return ok && an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,192 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func unmarshal(t *testing.T, s string) map[string]interface{} {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		assert.FailNow(t, "unmarshal", "%s: %v", s, err)
+	}
+	return m
+}
+
+// TestApply contains examples from appendix A of RFC 6902
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "adding an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "adding an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "removing an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "removing an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "replacing a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "moving a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "moving an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:  "copying a value",
+			doc:   `{"foo": {"bar": "baz"}}`,
+			patch: `[{"op": "copy", "from": "/foo", "path": "/qux"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"bar": "baz"}}`,
+		},
+		{
+			name:  "testing a value: success",
+			doc:   `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			want:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:    "testing a value: error",
+			doc:     `{"baz": "qux"}`,
+			patch:   `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "adding a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:    "adding to a nonexistent target",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "~ escape ordering",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "adding an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:    "removing a missing key",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "remove", "path": "/baz"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "a failed operation fails the whole patch",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "add", "path": "/baz", "value": "qux"}, {"op": "replace", "path": "/missing", "value": 1}]`,
+			wantErr: true,
+		},
+		{
+			name:  "adding a null value",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": null}]`,
+			want:  `{"foo": "bar", "baz": null}`,
+		},
+		{
+			name:    "adding without a value",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "add", "path": "/baz"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "replacing without a value",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "replace", "path": "/foo"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "testing without a value",
+			doc:     `{"foo": null}`,
+			patch:   `[{"op": "test", "path": "/foo"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported operation",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "merge", "path": "/foo", "value": 1}]`,
+			wantErr: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
+			doc := unmarshal(t, tt.doc)
+			p, err := Decode([]byte(tt.patch))
+			assert.NoError(t, err, "Decode()")
+
+			got, err := Apply(doc, p)
+			if (err != nil) != tt.wantErr {
+				assert.FailNow(t, "Apply()", "error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, unmarshal(t, tt.doc), doc, "Apply() must not modify doc")
+			if tt.want != "" {
+				assert.Equal(t, unmarshal(t, tt.want), got)
+			}
+		})
+	}
+}
+
+// TestApplyNumbers checks that test operations compare numbers decoded as
+// different Go types (e.g. from YAML rather than JSON) by value
+func TestApplyNumbers(t *testing.T) {
+	doc := map[string]interface{}{"a": int8(1), "b": uint(2), "c": float32(0.5), "d": []interface{}{uint16(3), int32(4)}}
+	p, err := Decode([]byte(`[{"op": "test", "path": "/a", "value": 1}, {"op": "test", "path": "/b", "value": 2}, {"op": "test", "path": "/c", "value": 0.5}, {"op": "test", "path": "/d", "value": [3, 4]}]`))
+	assert.NoError(t, err, "Decode()")
+
+	_, err = Apply(doc, p)
+	assert.NoError(t, err, "Apply()")
+}
+
+func TestOperationMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Patch{
+		{Op: OpAdd, Path: "/a", Value: nil},
+		{Op: OpRemove, Path: "/b"},
+		{Op: OpMove, From: "/c", Path: "/d"},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"op": "add", "path": "/a", "value": null}, {"op": "remove", "path": "/b"}, {"op": "move", "from": "/c", "path": "/d"}]`, string(b))
+}
@@ -0,0 +1,113 @@
+package patch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pointer is a parsed JSON Pointer (RFC 6901) stored as a list of
+// unescaped reference tokens; the empty Pointer refers to the whole document
+type Pointer []string
+
+// ParsePointer splits a JSON Pointer string (e.g. /spec/containers/0/args)
+// into its unescaped reference tokens
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %#v: must be empty or start with '/'", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Key returns a new Pointer extending p with the map key k
+func (p Pointer) Key(k string) Pointer {
+	result := make(Pointer, len(p), len(p)+1)
+	copy(result, p)
+	return append(result, k)
+}
+
+// String returns the escaped representation of p (e.g. /spec/containers/0/args)
+func (p Pointer) String() string {
+	sb := strings.Builder{}
+	for _, t := range p {
+		sb.WriteString("/")
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// get returns the value at p within doc
+func (p Pointer) get(doc interface{}) (interface{}, error) {
+	v := doc
+	for _, t := range p {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			child, ok := c[t]
+			if !ok {
+				return nil, fmt.Errorf("key %#v not found", t)
+			}
+			v = child
+		case []interface{}:
+			n, err := arrayIndex(t, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			v = c[n]
+		default:
+			return nil, fmt.Errorf("cannot reference %#v inside a %T", t, v)
+		}
+	}
+	return v, nil
+}
+
+// update walks doc to the parent of the value at p, replaces that parent
+// with the result of fn and returns the updated doc; fn receives the parent
+// container and the last reference token of p
+func (p Pointer) update(doc interface{}, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("the document root cannot be the target of this operation")
+	}
+	if len(p) == 1 {
+		return fn(doc, p[0])
+	}
+
+	child, err := p[:1].get(doc)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := p[1:].update(child, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		c[p[0]] = updated
+	case []interface{}:
+		n, _ := arrayIndex(p[0], len(c)-1)
+		c[n] = updated
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index token no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.HasPrefix(token, "+") || strings.HasPrefix(token, "-") {
+		return 0, fmt.Errorf("invalid array index %#v", token)
+	}
+	n, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %#v", token)
+	}
+	if n > max {
+		return 0, fmt.Errorf("array index %d out of bounds", n)
+	}
+	return n, nil
+}
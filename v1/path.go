@@ -0,0 +1,126 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Path is the location of a value within a tree of maps and slices,
+// stored as a list of segments where map keys are stored as-is and
+// array indexes are stored in brackets (e.g. spec.containers[0].args
+// is stored as ["spec", "containers", "[0]", "args"])
+type Path []string
+
+// ParsePath splits a dotted path string (e.g. spec.containers[*].args)
+// into its segments
+func ParsePath(s string) Path {
+	p := make(Path, 0)
+	segment := strings.Builder{}
+	flush := func() {
+		if segment.Len() > 0 {
+			p = append(p, segment.String())
+			segment.Reset()
+		}
+	}
+	for _, r := range s {
+		switch r {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			segment.WriteRune(r)
+		case ']':
+			segment.WriteRune(r)
+			flush()
+		default:
+			segment.WriteRune(r)
+		}
+	}
+	flush()
+	return p
+}
+
+// Key returns a new Path extending p with the map key k
+func (p Path) Key(k string) Path {
+	return p.append(k)
+}
+
+// Index returns a new Path extending p with the array index i
+func (p Path) Index(i int) Path {
+	return p.append(fmt.Sprintf("[%d]", i))
+}
+
+// identity returns a new Path extending p with a segment identifying the
+// hash within an array whose key field has the value id (e.g. [name=web])
+func (p Path) identity(key, id string) Path {
+	return p.append(fmt.Sprintf("[%s=%s]", key, id))
+}
+
+// append returns a new Path extending p with segment s without sharing p's backing array
+func (p Path) append(s string) Path {
+	result := make(Path, len(p), len(p)+1)
+	copy(result, p)
+	return append(result, s)
+}
+
+// String returns the dotted representation of p (e.g. spec.containers[0].args)
+func (p Path) String() string {
+	sb := strings.Builder{}
+	for i, s := range p {
+		if i > 0 && !isIndexSegment(s) {
+			sb.WriteString(".")
+		}
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
+
+// MarshalText writes p in its dotted representation (e.g. in JSON and YAML output)
+func (p Path) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText parses p from its dotted representation
+func (p *Path) UnmarshalText(b []byte) error {
+	*p = ParsePath(string(b))
+	return nil
+}
+
+// Match reports whether p matches pattern, a dotted path in which "*"
+// matches any single map key, "[*]" matches any single array index and
+// "**" matches any number of segments
+func (p Path) Match(pattern string) bool {
+	return matchSegments(ParsePath(pattern), p)
+}
+
+func matchSegments(pattern, p Path) bool {
+	if len(pattern) == 0 {
+		return len(p) == 0
+	}
+	switch head := pattern[0]; {
+	case head == "**":
+		for i := 0; i <= len(p); i++ {
+			if matchSegments(pattern[1:], p[i:]) {
+				return true
+			}
+		}
+		return false
+	case len(p) == 0:
+		return false
+	case head == "*":
+		if isIndexSegment(p[0]) {
+			return false
+		}
+	case head == "[*]":
+		if !isIndexSegment(p[0]) {
+			return false
+		}
+	case head != p[0]:
+		return false
+	}
+	return matchSegments(pattern[1:], p[1:])
+}
+
+func isIndexSegment(s string) bool {
+	return strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]")
+}
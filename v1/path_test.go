@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		have string
+		want Path
+	}{
+		{have: "", want: Path{}},
+		{have: "features", want: Path{"features"}},
+		{have: "spec.containers[0].args", want: Path{"spec", "containers", "[0]", "args"}},
+		{have: "spec.containers[*].args", want: Path{"spec", "containers", "[*]", "args"}},
+		{have: "matrix[0][1]", want: Path{"matrix", "[0]", "[1]"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.have), func(t *testing.T) {
+			got := ParsePath(tt.have)
+			assert.Equal(t, tt.want, got, "ParsePath()")
+			assert.Equal(t, tt.have, got.String(), "Path.String()")
+		})
+	}
+}
+
+func TestPathMatch(t *testing.T) {
+	tests := []struct {
+		path    Path
+		pattern string
+		want    bool
+	}{
+		{path: Path{"features"}, pattern: "features", want: true},
+		{path: Path{"features"}, pattern: "other", want: false},
+		{path: Path{"spec", "containers", "[0]", "args"}, pattern: "spec.containers[*].args", want: true},
+		{path: Path{"spec", "containers", "[0]", "args"}, pattern: "spec.containers.*.args", want: false},
+		{path: Path{"spec", "containers", "[0]", "args"}, pattern: "spec.*[*].args", want: true},
+		{path: Path{"spec", "containers", "[0]", "args"}, pattern: "**.args", want: true},
+		{path: Path{"args"}, pattern: "**.args", want: true},
+		{path: Path{"spec", "containers"}, pattern: "spec.**", want: true},
+		{path: Path{"spec", "containers"}, pattern: "spec.containers.args", want: false},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.pattern), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.path.Match(tt.pattern), "%s.Match(%#v)", tt.path, tt.pattern)
+		})
+	}
+}
+
+func TestPathDoesNotShareState(t *testing.T) {
+	parent := make(Path, 0, 10).Key("spec")
+	a := parent.Key("a")
+	b := parent.Key("b")
+	assert.Equal(t, "spec.a", a.String())
+	assert.Equal(t, "spec.b", b.String())
+}
@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Provenance maps the dotted Path of each leaf value in a merged result
+// to the Origin of that value
+//
+// a Provenance is filled in by merging with Config.WithProvenance and
+// can be carried across a chain of merges to record which named source
+// (e.g. default.yaml, dev.yaml, dev.us-east-1.yaml) wrote each value
+type Provenance map[string]*Origin
+
+// Origin describes the named source which last wrote a value and the
+// earlier contributions that value overrode
+type Origin struct {
+	Source    string         `json:"source"`
+	Value     interface{}    `json:"value"`
+	Deleted   bool           `json:"deleted,omitempty"`
+	Overrides []Contribution `json:"overrides,omitempty"`
+}
+
+// Contribution is a value written (or a key deleted) by a named source
+type Contribution struct {
+	Source  string      `json:"source"`
+	Value   interface{} `json:"value"`
+	Deleted bool        `json:"deleted,omitempty"`
+}
+
+// NewProvenance returns an empty Provenance
+func NewProvenance() Provenance {
+	return make(Provenance)
+}
+
+// Seed records every leaf value in m as written by source; use it to
+// record the origin of the dest map at the start of a chain of merges
+func (p Provenance) Seed(source string, m map[string]interface{}) Provenance {
+	for k, v := range m {
+		p.recordTree(Path{k}, source, v)
+	}
+	return p
+}
+
+// Copy returns a copy of p which can be extended without changing p
+func (p Provenance) Copy() Provenance {
+	if p == nil {
+		return nil
+	}
+	c := make(Provenance, len(p))
+	for k, o := range p {
+		oc := *o
+		oc.Value = DeepCopy(o.Value)
+		oc.Overrides = append([]Contribution(nil), o.Overrides...)
+		c[k] = &oc
+	}
+	return c
+}
+
+// Chain returns every Contribution to the value at path in the order they were merged; the last one wins
+func (o *Origin) Chain() []Contribution {
+	return append(append([]Contribution(nil), o.Overrides...), Contribution{Source: o.Source, Value: o.Value, Deleted: o.Deleted})
+}
+
+// record notes that source wrote the leaf value v at path
+func (p Provenance) record(path Path, source string, v interface{}) {
+	key := path.String()
+	p.removeDescendants(key)
+
+	origin := &Origin{Source: source, Value: DeepCopy(v)}
+	if previous, ok := p[key]; ok {
+		origin.Overrides = previous.Chain()
+	}
+	p[key] = origin
+}
+
+// recordDeletion notes that source deleted the key at path along with everything below it
+func (p Provenance) recordDeletion(path Path, source string) {
+	key := path.String()
+	p.removeDescendants(key)
+
+	origin := &Origin{Source: source, Deleted: true}
+	if previous, ok := p[key]; ok {
+		origin.Overrides = previous.Chain()
+	}
+	p[key] = origin
+}
+
+// recordTree notes that source wrote v at path, recording each leaf when v is a map
+func (p Provenance) recordTree(path Path, source string, v interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		p.record(path, source, v)
+		return
+	}
+
+	key := path.String()
+	delete(p, key)
+	p.removeDescendants(key)
+	for k, child := range m {
+		p.recordTree(path.Key(k), source, child)
+	}
+}
+
+// removeDescendants forgets the origins of values nested below key
+func (p Provenance) removeDescendants(key string) {
+	for k := range p {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			delete(p, k)
+		}
+	}
+}
+
+// recordDeletion notes that the key at path was removed when provenance is being tracked
+func (c *Config) recordDeletion(path Path) {
+	if c.provenance != nil {
+		c.provenance.recordDeletion(path, c.provenanceSource)
+	}
+}
+
+// recordMerge notes the result of merging src over prev at path when provenance is being tracked
+func (c *Config) recordMerge(path Path, src, prev interface{}, hadPrev bool, result interface{}) {
+	if c.provenance == nil {
+		return
+	}
+	if src == nil && !c.MergeNilValues {
+		// nil values are skipped so dest is untouched
+		return
+	}
+	if isHash(src) && isHash(result) && (!hadPrev || isHash(prev)) {
+		// hashes were merged recursively so their leaves are already recorded
+		return
+	}
+	if hadPrev && reflect.DeepEqual(prev, result) && !reflect.DeepEqual(src, result) {
+		// src was not applied (e.g. unmergeable values were preserved)
+		return
+	}
+	c.provenance.recordTree(path, c.provenanceSource, result)
+}
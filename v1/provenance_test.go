@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	defaults, err := rubyHashToMap(`{"log_level": "WARN", "env": "REQUIRED", "auth": {"username": "default_user", "password": "default_pass"}, "hosts": ["a"], "region": "unknown"}`)
+	assert.NoError(t, err)
+	dev, err := rubyHashToMap(`{"log_level": "DEBUG", "env": "dev", "auth": {"password": "dev_pass"}, "hosts": ["b"], "db": {"name": "dev_db"}}`)
+	assert.NoError(t, err)
+	devUsEast1, err := rubyHashToMap(`{"region": "us-east-1", "auth": "disabled", "db": {"name": "dev_db"}}`)
+	assert.NoError(t, err)
+
+	p := NewProvenance().Seed("default.yaml", defaults)
+	r, err := MergeCopyWithOptions(dev, defaults, NewConfigDeeperMergeBang().WithProvenance("dev.yaml", p))
+	assert.NoError(t, err)
+	devProvenance := p.Copy()
+	_, err = MergeCopyWithOptions(devUsEast1, r, NewConfigDeeperMergeBang().WithProvenance("dev.us-east-1.yaml", p))
+	assert.NoError(t, err)
+
+	assert.Equal(t, &Origin{
+		Source: "dev.yaml",
+		Value:  "DEBUG",
+		Overrides: []Contribution{
+			{Source: "default.yaml", Value: "WARN"},
+		},
+	}, p["log_level"], "scalar overridden once")
+
+	assert.Equal(t, &Origin{
+		Source: "dev.us-east-1.yaml",
+		Value:  "us-east-1",
+		Overrides: []Contribution{
+			{Source: "default.yaml", Value: "unknown"},
+		},
+	}, p["region"], "scalar skipping a layer")
+
+	assert.Equal(t, &Origin{
+		Source: "dev.yaml",
+		Value:  []interface{}{"a", "b"},
+		Overrides: []Contribution{
+			{Source: "default.yaml", Value: []interface{}{"a"}},
+		},
+	}, p["hosts"], "arrays are recorded as a single value")
+
+	assert.Equal(t, &Origin{
+		Source: "dev.us-east-1.yaml",
+		Value:  "dev_db",
+		Overrides: []Contribution{
+			{Source: "dev.yaml", Value: "dev_db"},
+		},
+	}, p["db.name"], "new nested key written twice")
+
+	assert.Equal(t, &Origin{Source: "dev.us-east-1.yaml", Value: "disabled"}, p["auth"], "hash replaced by a scalar")
+	assert.NotContains(t, p, "auth.password", "hash replaced by a scalar")
+	assert.NotContains(t, p, "auth.username", "hash replaced by a scalar")
+
+	assert.Equal(t, &Origin{
+		Source: "dev.yaml",
+		Value:  "dev_pass",
+		Overrides: []Contribution{
+			{Source: "default.yaml", Value: "default_pass"},
+		},
+	}, devProvenance["auth.password"], "copies are not changed by later merges")
+	assert.Equal(t, &Origin{Source: "default.yaml", Value: "default_user"}, devProvenance["auth.username"], "untouched keys keep their seeded origin")
+}
+
+func TestProvenancePreservedUnmergeables(t *testing.T) {
+	src, err := rubyHashToMap(`{"property" => "1", "other" => "2"}`)
+	assert.NoError(t, err)
+	dest, err := rubyHashToMap(`{"property" => {"bedroom_count" => ["2"]}}`)
+	assert.NoError(t, err)
+
+	p := NewProvenance().Seed("dest", dest)
+	_, err = MergeCopyWithOptions(src, dest, NewConfigDeeperMergeBang().WithPreserveUnmergeables(true).WithProvenance("src", p))
+	assert.NoError(t, err)
+
+	assert.Equal(t, Provenance{
+		"property.bedroom_count": {Source: "dest", Value: []interface{}{"2"}},
+		"other":                  {Source: "src", Value: "2"},
+	}, p)
+}
+
+func TestProvenanceKnockoutKeys(t *testing.T) {
+	dest, err := rubyHashToMap(`{"log_level": "WARN", "auth": {"username": "default_user"}}`)
+	assert.NoError(t, err)
+	src, err := rubyHashToMap(`{"--log_level": nil, "--auth": nil}`)
+	assert.NoError(t, err)
+
+	p := NewProvenance().Seed("default.yaml", dest)
+	r, err := MergeCopyWithOptions(src, dest, NewConfigDeeperMergeBang().WithDefaultKnockoutPrefix().WithProvenance("dev.yaml", p))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{}, r)
+
+	assert.Equal(t, Provenance{
+		"log_level": {
+			Source:    "dev.yaml",
+			Deleted:   true,
+			Overrides: []Contribution{{Source: "default.yaml", Value: "WARN"}},
+		},
+		"auth": {
+			Source:  "dev.yaml",
+			Deleted: true,
+		},
+	}, p)
+}
+
+func TestProvenanceMergePatch(t *testing.T) {
+	dest, err := UnmarshallJSONToMap(`{"log_level": "WARN", "auth": {"username": "default_user", "password": "default_pass"}}`)
+	assert.NoError(t, err)
+	src, err := UnmarshallJSONToMap(`{"log_level": null, "auth": {"password": null}, "region": null}`)
+	assert.NoError(t, err)
+
+	p := NewProvenance().Seed("default.yaml", dest)
+	r, err := MergeCopyWithOptions(src, dest, NewConfigMergePatch().WithProvenance("dev.yaml", p))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"auth": map[string]interface{}{"username": "default_user"}}, r)
+
+	assert.Equal(t, Provenance{
+		"log_level": {
+			Source:    "dev.yaml",
+			Deleted:   true,
+			Overrides: []Contribution{{Source: "default.yaml", Value: "WARN"}},
+		},
+		"auth.username": {Source: "default.yaml", Value: "default_user"},
+		"auth.password": {
+			Source:    "dev.yaml",
+			Deleted:   true,
+			Overrides: []Contribution{{Source: "default.yaml", Value: "default_pass"}},
+		},
+	}, p)
+}
@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Strategy names a set of array merge options which can be attached to
+// the paths of a tree with Config.WithPathStrategy
+type Strategy string
+
+const (
+	// StrategyReplace overwrites dest arrays with src arrays
+	StrategyReplace Strategy = "replace"
+
+	// StrategyUnion combines src and dest arrays without duplicates
+	StrategyUnion Strategy = "union"
+
+	// StrategyUnionSorted combines src and dest arrays without duplicates and sorts the result
+	StrategyUnionSorted Strategy = "union-sorted"
+
+	// StrategyAppend appends src arrays to dest arrays keeping duplicates
+	StrategyAppend Strategy = "append"
+
+	// StrategyMergeByIndex merges hashes within arrays by their position
+	StrategyMergeByIndex Strategy = "merge-by-index"
+
+	// strategyMergeByKeyPrefix prefixes the field name of a merge-by-key strategy
+	strategyMergeByKeyPrefix = "merge-by-key:"
+)
+
+// StrategyMergeByKey merges hashes within arrays by matching the value of the key field
+func StrategyMergeByKey(key string) Strategy {
+	return Strategy(strategyMergeByKeyPrefix + key)
+}
+
+// ParseStrategy validates s as the name of a Strategy (e.g. "replace" or "merge-by-key:name")
+func ParseStrategy(s string) (Strategy, error) {
+	strategy := Strategy(s)
+	if err := strategy.apply(NewConfig()); err != nil {
+		return "", err
+	}
+	return strategy, nil
+}
+
+// PathStrategy pairs a path pattern (see Path.Match) with the Strategy used to merge values at that path
+type PathStrategy struct {
+	Pattern  string
+	Strategy Strategy
+}
+
+// apply sets the array options on c which implement the Strategy
+func (s Strategy) apply(c *Config) error {
+	c.OverwriteArrays = false
+	c.KeepArrayDuplicates = false
+	c.SortMergedArrays = false
+	c.MergeHashArrays = false
+	c.MergeHashArraysByKey = nil
+
+	switch {
+	case s == StrategyReplace:
+		c.OverwriteArrays = true
+	case s == StrategyUnion:
+	case s == StrategyUnionSorted:
+		c.SortMergedArrays = true
+	case s == StrategyAppend:
+		c.KeepArrayDuplicates = true
+	case s == StrategyMergeByIndex:
+		c.MergeHashArrays = true
+	case strings.HasPrefix(string(s), strategyMergeByKeyPrefix) && len(s) > len(strategyMergeByKeyPrefix):
+		key := strings.TrimPrefix(string(s), strategyMergeByKeyPrefix)
+		c.MergeHashArraysByKey = &key
+	default:
+		return fmt.Errorf("unrecognized merge strategy %#v: supported strategies are: %s", string(s), strings.Join([]string{
+			string(StrategyReplace),
+			string(StrategyUnion),
+			string(StrategyUnionSorted),
+			string(StrategyAppend),
+			string(StrategyMergeByIndex),
+			strategyMergeByKeyPrefix + "<field>",
+		}, ", "))
+	}
+	return nil
+}
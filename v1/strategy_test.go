@@ -0,0 +1,18 @@
+package v1
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseStrategy(t *testing.T) {
+	for _, s := range []string{"replace", "union", "union-sorted", "append", "merge-by-index", "merge-by-key:name"} {
+		got, err := ParseStrategy(s)
+		assert.NoError(t, err, "ParseStrategy(%#v)", s)
+		assert.Equal(t, Strategy(s), got)
+	}
+	for _, s := range []string{"", "bogus", "merge-by-key:"} {
+		_, err := ParseStrategy(s)
+		assert.Error(t, err, "ParseStrategy(%#v)", s)
+	}
+}